@@ -0,0 +1,153 @@
+package wfc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// AdjacencyGraph holds, for every input module and direction, the indices of
+// the input modules that may be placed next to it in that direction. Indices
+// refer to positions in Wave.Input.
+type AdjacencyGraph [][4][]int
+
+// AnalysisReport summarizes how constrained a tileset is. See Analyze.
+type AnalysisReport struct {
+	AverageBranching float64   // Average number of possible neighbors per module and direction
+	MinNeighbors     int       // Fewest possible neighbors of any module in any direction
+	MaxNeighbors     int       // Most possible neighbors of any module in any direction
+	Bottlenecks      []*Module // Modules with at most one possible neighbor in some direction
+}
+
+// AdjacencyGraph builds the adjacency graph of the input modules.
+func (w *Wave) AdjacencyGraph() AdjacencyGraph {
+	graph := make(AdjacencyGraph, len(w.Input))
+	for i, a := range w.Input {
+		for _, d := range Directions {
+			graph[i][d] = make([]int, 0)
+			for j, b := range w.Input {
+				if w.CanNeighbor(a, b, d) {
+					graph[i][d] = append(graph[i][d], j)
+				}
+			}
+		}
+	}
+	return graph
+}
+
+// Analyze estimates how difficult the wave will be to collapse, without
+// modifying it. A low average branching factor or a large number of
+// bottlenecks hint that more attempts will be needed, or that the tileset is
+// likely to produce contradictions.
+func (w *Wave) Analyze() AnalysisReport {
+	var report AnalysisReport
+
+	graph := w.AdjacencyGraph()
+	if len(graph) == 0 {
+		return report
+	}
+
+	total := 0
+	report.MinNeighbors = len(w.Input)
+	for i, edges := range graph {
+		bottleneck := false
+		for _, d := range Directions {
+			n := len(edges[d])
+			total += n
+			if n < report.MinNeighbors {
+				report.MinNeighbors = n
+			}
+			if n > report.MaxNeighbors {
+				report.MaxNeighbors = n
+			}
+			if n <= 1 {
+				bottleneck = true
+			}
+		}
+		if bottleneck {
+			report.Bottlenecks = append(report.Bottlenecks, w.Input[i])
+		}
+	}
+
+	report.AverageBranching = float64(total) / float64(len(graph)*len(Directions))
+
+	return report
+}
+
+// ValidModulesGiven returns the input modules that fit into an empty slot with
+// the given neighbors, keyed by their direction from the slot, e.g. to grey out
+// the tiles of a palette that can't be placed at a position. Missing or nil
+// neighbors don't restrict the result. Modules are checked with
+// Wave.CanNeighbor and returned in input order; the wave is not modified.
+func (w *Wave) ValidModulesGiven(neighbors map[Direction]*Module) []*Module {
+	res := make([]*Module, 0)
+	for _, m := range w.Input {
+		fits := true
+		for d, n := range neighbors {
+			if n != nil && !w.CanNeighbor(m, n, d) {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// OptimizeTileset finds input modules that are interchangeable as far as the
+// adjacency rules go: modules in the same group may be placed next to exactly
+// the same modules in every direction. Such a group can be merged into a
+// single module whose weight is the sum of the group's weights, which speeds
+// up the collapse of large tilesets without changing which layouts are
+// possible; the concrete tile can be picked afterwards. Groups are returned
+// in the order of their first module in the input, and modules without an
+// equivalent aren't reported.
+func (w *Wave) OptimizeTileset() [][]*Module {
+	groups := make(map[string][]*Module)
+	order := make([]string, 0)
+	for i, edges := range w.AdjacencyGraph() {
+		key := fmt.Sprint(edges)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], w.Input[i])
+	}
+
+	res := make([][]*Module, 0)
+	for _, key := range order {
+		if len(groups[key]) > 1 {
+			res = append(res, groups[key])
+		}
+	}
+	return res
+}
+
+// ExportAdjacencyDOT writes the adjacency graph (see AdjacencyGraph) in the
+// Graphviz DOT format, e.g. to render it with:
+//
+//	dot -Tsvg tileset.dot -o tileset.svg
+//
+// Nodes are the input modules, labeled with their index. An edge from "a" to
+// "b" labeled Right means "b" may be placed to the right of "a", likewise for
+// Down. The Left and Up relationships are the same edges read backwards, so
+// they are left out.
+func (w *Wave) ExportAdjacencyDOT(out io.Writer) error {
+	b := bufio.NewWriter(out)
+
+	fmt.Fprintln(b, "digraph adjacency {")
+	for i := range w.Input {
+		fmt.Fprintf(b, "\t%d [label=\"%d\"];\n", i, w.Input[i].Index)
+	}
+	for i, edges := range w.AdjacencyGraph() {
+		for _, d := range []Direction{Right, Down} {
+			for _, j := range edges[d] {
+				fmt.Fprintf(b, "\t%d -> %d [label=\"%s\"];\n", i, j, d.ToString())
+			}
+		}
+	}
+	fmt.Fprintln(b, "}")
+
+	return b.Flush()
+}
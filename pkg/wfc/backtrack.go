@@ -0,0 +1,192 @@
+package wfc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Number of rounds CollapseAuto makes, doubling the backtracking budget each
+// round.
+const autoRounds = 5
+
+// errBacktrackBudget is returned when backtracking gave up before exhausting
+// all options.
+var errBacktrackBudget = errors.New("backtracking budget exhausted")
+
+// decision is a single collapse decision made while backtracking, along with
+// the state of the wave right before it was made.
+type decision struct {
+	state  [][]*Module
+	log    int // Length of the decision log before the decision
+	slot   *Slot
+	module *Module
+}
+
+// CollapseAuto collapses the wave until it succeeds, without having to guess
+// an attempt count.
+//
+// Contradictions are resolved by backtracking: the most recent decision is
+// undone and its module ruled out for that slot. If the backtracking budget
+// (initially one backtrack per slot) runs out, the wave is reset to the state
+// it had when CollapseAuto was called and collapsed again with twice the
+// budget, up to a fixed number of rounds.
+//
+// Returns ErrNoSolution if the tileset genuinely can't be solved from the
+// current state, or if every round gave up.
+func (w *Wave) CollapseAuto() error {
+	if err := w.checkInitialized(); err != nil {
+		return err
+	}
+
+	start := w.snapshot()
+	budget := len(w.PossibilitySpace)
+
+	var err error
+	for round := 0; round < autoRounds; round++ {
+		err = w.collapseBacktracking(context.Background(), budget)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errBacktrackBudget) {
+			return err
+		}
+		if round < autoRounds-1 {
+			w.restore(start)
+		}
+		budget *= 2
+	}
+
+	return ErrNoSolution
+}
+
+// CollapseContext collapses the wave like CollapseAuto, but keeps retrying with
+// a growing backtracking budget until it succeeds or the context is done. Each
+// retry starts over from the state the wave had when CollapseContext was
+// called, and continues with the wave's random source, so it explores a
+// different solution.
+//
+// Returns ErrNoSolution if the tileset genuinely can't be solved from the
+// current state. If the context is done first, its error is returned and the
+// wave is left in the partially collapsed state of the interrupted attempt.
+func (w *Wave) CollapseContext(ctx context.Context) error {
+	if err := w.checkInitialized(); err != nil {
+		return err
+	}
+
+	start := w.snapshot()
+	budget := len(w.PossibilitySpace)
+
+	for {
+		err := w.collapseBacktracking(ctx, budget)
+		if !errors.Is(err, errBacktrackBudget) {
+			return err
+		}
+		w.restore(start)
+		if budget < 1<<30 {
+			budget *= 2
+		}
+	}
+}
+
+// CollapseDeadline collapses the wave with CollapseContext, giving up after
+// the given duration. Use this instead of guessing an attempt count, e.g. in
+// interactive applications.
+func (w *Wave) CollapseDeadline(d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return w.CollapseContext(ctx)
+}
+
+// collapseBacktracking collapses the wave, undoing decisions that lead to a
+// contradiction. It gives up after the given number of backtracks, or when the
+// context is done.
+func (w *Wave) collapseBacktracking(ctx context.Context, budget int) error {
+	stack := make([]decision, 0)
+	backtracks := 0
+
+	for !w.IsCollapsed() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		state := w.snapshot()
+		log := len(w.decisions)
+		slot := w.nextSlot()
+		if slot == nil {
+			return nil
+		}
+		w.decide(slot)
+		stack = append(stack, decision{state: state, log: log, slot: slot, module: slot.Superposition[0]})
+
+		err := w.propagate(slot)
+		for err != nil {
+			if len(stack) == 0 {
+				return ErrNoSolution
+			}
+			if backtracks >= budget {
+				return errBacktrackBudget
+			}
+			backtracks++
+
+			// Undo the last decision and rule out the module it chose
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			w.restore(last.state)
+			w.decisions = w.decisions[:last.log]
+
+			if w.Logger != nil {
+				w.Logger.Debug("backtrack",
+					"x", last.slot.X, "y", last.slot.Y, "module", last.module.Index)
+			}
+
+			last.slot.Superposition = removeModule(last.slot.Superposition, last.module)
+			if len(last.slot.Superposition) == 0 {
+				err = ErrNoSolution
+				continue
+			}
+			w.updateEntropy(last.slot)
+			w.markCollapsed(last.slot)
+			err = w.propagate(last.slot)
+		}
+	}
+
+	return nil
+}
+
+// snapshot returns a copy of the superposition of every slot.
+func (w *Wave) snapshot() [][]*Module {
+	state := make([][]*Module, len(w.PossibilitySpace))
+	for i, s := range w.PossibilitySpace {
+		state[i] = make([]*Module, len(s.Superposition))
+		copy(state[i], s.Superposition)
+	}
+	return state
+}
+
+// restore sets the superposition of every slot back to a snapshot.
+func (w *Wave) restore(state [][]*Module) {
+	for i, s := range w.PossibilitySpace {
+		s.Superposition = make([]*Module, len(state[i]))
+		copy(s.Superposition, state[i])
+		if len(s.Superposition) != 1 {
+			s.CollapsedAt = -1
+		}
+	}
+	w.History = make([]*Slot, 0)
+	w.entropy = nil
+	w.patternQueue = nil
+	w.placed = nil
+	w.placedCount = nil
+}
+
+// removeModule returns a copy of the list without the given module.
+func removeModule(modules []*Module, m *Module) []*Module {
+	res := make([]*Module, 0, len(modules))
+	for _, o := range modules {
+		if o != m {
+			res = append(res, o)
+		}
+	}
+	return res
+}
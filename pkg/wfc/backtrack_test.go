@@ -0,0 +1,70 @@
+package wfc
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestCollapseAutoBacktracks(t *testing.T) {
+	differ := func(a, b int, d Direction) bool { return a != b }
+
+	tests := []struct {
+		name    string
+		modules int
+		size    int
+		wrap    bool
+		seed    int
+		hint    bool
+		err     error
+	}{
+		// Three colors contradict without backtracking for this seed
+		{"three colors", 3, 8, false, 3, false, nil},
+		{"three colors with hint", 3, 8, false, 6, true, nil},
+		{"two colors", 2, 8, false, 3, false, nil},
+		// Two colors can't alternate around an odd ring
+		{"odd ring", 2, 3, true, 1, false, ErrNoSolution},
+	}
+
+	for _, tt := range tests {
+		w := NewSymbolic(tt.modules, differ, tt.size, tt.size)
+		w.WrapX, w.WrapY = tt.wrap, tt.wrap
+		if tt.hint {
+			hints := map[image.Point][]*Module{{3, 3}: {w.Input[2]}}
+			if err := w.InitializeWithHints(tt.seed, hints); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		} else {
+			w.Initialize(tt.seed)
+		}
+
+		err := w.CollapseAuto()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: CollapseAuto() = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if tt.err != nil {
+			continue
+		}
+
+		if !w.IsCollapsed() {
+			t.Errorf("%s: wave is not collapsed", tt.name)
+		}
+		if c, found := w.leastImportantViolation(); found {
+			t.Errorf("%s: output violates the rules: %+v", tt.name, c)
+		}
+		if tt.hint && w.GetSlot(3, 3).Superposition[0] != w.Input[2] {
+			t.Errorf("%s: backtracking lost the hint", tt.name)
+		}
+	}
+}
+
+func TestCollapseAutoNeedsBacktracking(t *testing.T) {
+	// Guards the "three colors" case above: without backtracking, the
+	// seed runs into a contradiction
+	w := NewSymbolic(3, func(a, b int, d Direction) bool { return a != b }, 8, 8)
+	w.Initialize(3)
+	if err := w.collapseAll(); !errors.Is(err, ErrNoSolution) {
+		t.Errorf("collapse without backtracking = %v, want ErrNoSolution", err)
+	}
+}
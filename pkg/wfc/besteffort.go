@@ -0,0 +1,104 @@
+package wfc
+
+// CollapseBestEffort collapses the wave and always completes, even if the
+// tileset has no valid solution. The wave is collapsed with CollapseAuto
+// first. If that fails, every slot left in a contradiction or uncollapsed is
+// filled in scan order with the module that conflicts with the fewest of its
+// collapsed neighbors, ties going to the higher weight. Contradicted slots
+// pick from all modules allowed at their position.
+//
+// Returns the wave and the number of pairs of neighboring slots that violate
+// the adjacency constraints, 0 if the output is valid. Returns -1 if the wave
+// hasn't been initialized.
+func (w *Wave) CollapseBestEffort() (*Wave, int) {
+	if err := w.checkInitialized(); err != nil {
+		return w, -1
+	}
+	if err := w.CollapseAuto(); err == nil {
+		return w, 0
+	}
+
+	w.fillBestEffort()
+	return w, w.violations()
+}
+
+// fillBestEffort fills every slot left in a contradiction or uncollapsed with
+// the module that conflicts with the fewest of its collapsed neighbors, see
+// CollapseBestEffort.
+func (w *Wave) fillBestEffort() {
+	for y := 0; y < w.Height; y++ {
+		for x := 0; x < w.Width; x++ {
+			s := w.GetSlot(x, y)
+			if len(s.Superposition) == 1 {
+				continue
+			}
+
+			candidates := s.Superposition
+			if len(candidates) == 0 {
+				candidates = w.allowedAt(x, y)
+			}
+			if len(candidates) == 0 {
+				continue
+			}
+
+			best, fewest := candidates[0], w.conflicts(s, candidates[0])
+			for _, m := range candidates[1:] {
+				n := w.conflicts(s, m)
+				if n < fewest || (n == fewest && m.weight() > best.weight()) {
+					best, fewest = m, n
+				}
+			}
+
+			if w.Logger != nil && fewest > 0 {
+				w.Logger.Debug("best effort",
+					"x", s.X, "y", s.Y, "module", best.Index, "conflicts", fewest)
+			}
+
+			w.steps++
+			s.Superposition = []*Module{best}
+			s.CollapsedAt = w.steps
+			w.recordPlaced(s)
+		}
+	}
+}
+
+// conflicts counts the collapsed neighbors of a slot that the given module
+// can't be placed next to.
+func (w *Wave) conflicts(s *Slot, m *Module) int {
+	candidate := &Slot{X: s.X, Y: s.Y, Superposition: []*Module{m}}
+	count := 0
+	for _, d := range Directions {
+		if !w.HasNeighbor(s, d) {
+			continue
+		}
+		n := w.GetNeighbor(s, d)
+		if len(n.Superposition) != 1 {
+			continue
+		}
+		if !w.isPossible(n.Superposition[0], candidate, n, d) {
+			count++
+		}
+	}
+	return count
+}
+
+// violations counts the pairs of neighboring collapsed slots that violate the
+// adjacency constraints.
+func (w *Wave) violations() int {
+	count := 0
+	for _, s := range w.PossibilitySpace {
+		if len(s.Superposition) != 1 {
+			continue
+		}
+		for _, d := range []Direction{Right, Down} {
+			if !w.HasNeighbor(s, d) {
+				continue
+			}
+			n := w.GetNeighbor(s, d)
+			if len(n.Superposition) == 1 && !w.isPossible(n.Superposition[0], s, n, d) {
+				count++
+			}
+		}
+	}
+	return count
+}
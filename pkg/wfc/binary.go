@@ -0,0 +1,92 @@
+package wfc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var (
+	ErrInvalidTilemap = errors.New("invalid binary tilemap")
+)
+
+// Header of the binary tilemap format, see WriteBinary.
+const (
+	tilemapMagic   = "WFCM"
+	tilemapVersion = 1
+)
+
+// Upper bound of the grid size accepted by ReadBinary, to reject corrupt
+// headers before allocating the grid.
+const maxTilemapSlots = 1 << 28
+
+// WriteBinary writes the grid of module indices (see ExportIndices) in a
+// compact binary format, for game engines that render the map from their own
+// copy of the tileset. Read it back with ReadBinary.
+//
+// The format is the magic "WFCM", a version byte, the width and height as
+// unsigned varints, followed by the index+1 of every slot in row-major order
+// as unsigned varints, where 0 marks a slot that isn't collapsed.
+func (w *Wave) WriteBinary(out io.Writer) error {
+	b := bufio.NewWriter(out)
+	buf := make([]byte, binary.MaxVarintLen64)
+
+	b.WriteString(tilemapMagic)
+	b.WriteByte(tilemapVersion)
+	b.Write(buf[:binary.PutUvarint(buf, uint64(w.Width))])
+	b.Write(buf[:binary.PutUvarint(buf, uint64(w.Height))])
+	for _, row := range w.ExportIndices() {
+		for _, idx := range row {
+			b.Write(buf[:binary.PutUvarint(buf, uint64(idx+1))])
+		}
+	}
+
+	return b.Flush()
+}
+
+// ReadBinary reads a grid of module indices written by WriteBinary, indexed
+// [y][x], with -1 for slots that weren't collapsed. Returns ErrInvalidTilemap
+// if the data isn't a binary tilemap or was written by a newer version.
+func ReadBinary(in io.Reader) ([][]int, error) {
+	b := bufio.NewReader(in)
+
+	header := make([]byte, len(tilemapMagic)+1)
+	if _, err := io.ReadFull(b, header); err != nil {
+		return nil, fmt.Errorf("reading header: %w", ErrInvalidTilemap)
+	}
+	if string(header[:len(tilemapMagic)]) != tilemapMagic {
+		return nil, fmt.Errorf("bad magic: %w", ErrInvalidTilemap)
+	}
+	if v := header[len(tilemapMagic)]; v != tilemapVersion {
+		return nil, fmt.Errorf("unsupported version %d: %w", v, ErrInvalidTilemap)
+	}
+
+	width, err := binary.ReadUvarint(b)
+	if err != nil {
+		return nil, fmt.Errorf("reading width: %w", ErrInvalidTilemap)
+	}
+	height, err := binary.ReadUvarint(b)
+	if err != nil {
+		return nil, fmt.Errorf("reading height: %w", ErrInvalidTilemap)
+	}
+	if width > maxTilemapSlots || height > maxTilemapSlots || width*height > maxTilemapSlots {
+		return nil, fmt.Errorf("bad size %dx%d: %w", width, height, ErrInvalidTilemap)
+	}
+
+	grid := make([][]int, height)
+	for y := range grid {
+		grid[y] = make([]int, width)
+		for x := range grid[y] {
+			v, err := binary.ReadUvarint(b)
+			if err != nil || v > math.MaxInt32 {
+				return nil, fmt.Errorf("slot %d,%d: %w", x, y, ErrInvalidTilemap)
+			}
+			grid[y][x] = int(v) - 1
+		}
+	}
+
+	return grid, nil
+}
@@ -0,0 +1,62 @@
+package wfc
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	w := NewSymbolic(200, func(a, b int, d Direction) bool { return true }, 3, 2)
+	w.Initialize(1)
+	for i, s := range w.PossibilitySpace {
+		s.Superposition = []*Module{w.Input[i*37%200]}
+	}
+	// Uncollapsed and contradicting slots are both written as -1
+	w.GetSlot(1, 0).Superposition = w.Input[:2]
+	w.GetSlot(2, 1).Superposition = nil
+
+	var buf bytes.Buffer
+	if err := w.WriteBinary(&buf); err != nil {
+		t.Fatalf("WriteBinary: %v", err)
+	}
+	grid, err := ReadBinary(&buf)
+	if err != nil {
+		t.Fatalf("ReadBinary: %v", err)
+	}
+	if want := w.ExportIndices(); !reflect.DeepEqual(grid, want) {
+		t.Errorf("ReadBinary() = %v, want %v", grid, want)
+	}
+	if grid[0][1] != -1 || grid[1][2] != -1 {
+		t.Errorf("open slots read as %d and %d, want -1", grid[0][1], grid[1][2])
+	}
+}
+
+func TestReadBinaryRejectsCorruptData(t *testing.T) {
+	w := NewSymbolic(2, func(a, b int, d Direction) bool { return true }, 2, 2)
+	w.Initialize(1)
+	var buf bytes.Buffer
+	if err := w.WriteBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"short header", []byte("WFC")},
+		{"bad magic", append([]byte("WFCX"), valid[4:]...)},
+		{"newer version", append([]byte("WFCM\x02"), valid[5:]...)},
+		{"missing height", []byte("WFCM\x01\x02")},
+		{"oversized grid", []byte("WFCM\x01\xff\xff\xff\xff\x0f\xff\xff\xff\xff\x0f")},
+		{"truncated slots", valid[:len(valid)-1]},
+	}
+	for _, tt := range tests {
+		if _, err := ReadBinary(bytes.NewReader(tt.data)); !errors.Is(err, ErrInvalidTilemap) {
+			t.Errorf("%s: ReadBinary() = %v, want ErrInvalidTilemap", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package wfc
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// Upper bound of memoized superposition signatures, to keep memory in check
+// for tilesets with many modules.
+const maxCachedSignatures = 1 << 16
+
+// bitset is a set of module positions in Wave.Input.
+type bitset []uint64
+
+func (b bitset) has(i int) bool { return b[i/64]&(1<<(i%64)) != 0 }
+
+func (b bitset) set(i int) { b[i/64] |= 1 << (i % 64) }
+
+// possibleCache memoizes which input modules are possible next to a given
+// superposition in a given direction.
+type possibleCache struct {
+	version rulesVersion      // Rules the results were computed with
+	index   map[*Module]int   // Position of every module in Wave.Input
+	allowed map[string]bitset // Keyed by superposition signature and direction
+	small   map[smallKey]uint64
+}
+
+// rulesVersion identifies the state of the rules of the module and the wave.
+type rulesVersion struct {
+	module, wave uint64
+}
+
+// smallKey is the signature of a superposition for inputs of up to 64 modules,
+// which avoids building string keys.
+type smallKey struct {
+	sig uint64
+	d   Direction
+}
+
+// cachedPossibleModules returns the modules of slot "b" that are possible from
+// slot "a", using the memoized adjacency of the superposition of "a". The
+// second return value is false if the cache can't be used, either because a
+// custom IsPossibleFn is set or because a module is not part of the input.
+//
+// The cache is cleared by Initialize, and whenever the rules change: Forbid
+// and Allow bump a package-wide version, the rule changes of a wave (see
+// rulesChanged) bump the version of the wave.
+func (w *Wave) cachedPossibleModules(a, b *Slot, d Direction) ([]*Module, bool) {
+	if w.IsPossibleFn != nil {
+		return nil, false
+	}
+
+	version := rulesVersion{atomic.LoadUint64(&moduleRules), w.rules}
+	if w.possible != nil && w.possible.version != version {
+		w.possible = nil
+	}
+	if w.possible == nil {
+		w.possible = &possibleCache{
+			version: version,
+			index:   make(map[*Module]int, len(w.Input)),
+			allowed: make(map[string]bitset),
+			small:   make(map[smallKey]uint64),
+		}
+		for i, m := range w.Input {
+			w.possible.index[m] = i
+		}
+	}
+	c := w.possible
+
+	// The signature is the set of modules in slot "a" plus the direction
+	words := (len(w.Input) + 63) / 64
+	sig := make(bitset, words)
+	for _, m := range a.Superposition {
+		i, ok := c.index[m]
+		if !ok {
+			return nil, false
+		}
+		sig.set(i)
+	}
+
+	var allowed bitset
+	if words == 1 {
+		key := smallKey{sig: sig[0], d: d}
+		word, ok := c.small[key]
+		if !ok {
+			word = w.allowedFrom(a, d, words)[0]
+			if len(c.small) < maxCachedSignatures {
+				c.small[key] = word
+			}
+		}
+		allowed = bitset{word}
+	} else {
+		key := signatureKey(sig, d)
+		var ok bool
+		allowed, ok = c.allowed[key]
+		if !ok {
+			allowed = w.allowedFrom(a, d, words)
+			if len(c.allowed) < maxCachedSignatures {
+				c.allowed[key] = allowed
+			}
+		}
+	}
+
+	res := make([]*Module, 0)
+	for _, m := range b.Superposition {
+		i, ok := c.index[m]
+		if !ok {
+			return nil, false
+		}
+		if allowed.has(i) {
+			res = append(res, m)
+		}
+	}
+
+	return res, true
+}
+
+// allowedFrom returns the set of input modules that are possible next to the
+// given slot in the given direction.
+func (w *Wave) allowedFrom(a *Slot, d Direction, words int) bitset {
+	w.countChecks(len(w.Input))
+	allowed := make(bitset, words)
+	for i, m := range w.Input {
+		if w.possibleFrom(m, a, d) {
+			allowed.set(i)
+		}
+	}
+	return allowed
+}
+
+// isPossible checks the modules with IsPossibleFn, or like
+// DefaultIsPossibleFunc if it is nil.
+func (w *Wave) isPossible(m *Module, from, to *Slot, d Direction) bool {
+	if w.IsPossibleFn != nil {
+		return w.IsPossibleFn(m, from, to, d)
+	}
+	return w.possibleFrom(m, from, d)
+}
+
+// possibleFrom returns true if the given module is possible next to the given
+// slot in the given direction, like Module.IsPossibleFrom but with the rules of
+// the wave (see CanNeighbor).
+func (w *Wave) possibleFrom(m *Module, from *Slot, d Direction) bool {
+	if w.pairRules == nil {
+		return m.IsPossibleFrom(from, d)
+	}
+	for _, c := range from.Superposition {
+		if w.CanNeighbor(c, m, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// rulesChanged records a change of the rules of the wave, so the memoized
+// adjacency is computed again.
+func (w *Wave) rulesChanged() {
+	w.rules++
+}
+
+// signatureKey turns a superposition bitset and a direction into a map key.
+func signatureKey(sig bitset, d Direction) string {
+	var b strings.Builder
+	b.Grow(len(sig)*8 + 1)
+	b.WriteByte(byte(d))
+	for _, word := range sig {
+		for i := 0; i < 8; i++ {
+			b.WriteByte(byte(word >> (8 * i)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package wfc
+
+import "testing"
+
+func TestCachedPossibleModulesFollowRules(t *testing.T) {
+	w := NewSymbolic(2, func(a, b int, d Direction) bool { return true }, 2, 1)
+	w.Initialize(1)
+	m0, m1 := w.Input[0], w.Input[1]
+	a, b := w.GetSlot(0, 0), w.GetSlot(1, 0)
+	a.Superposition = []*Module{m0}
+
+	if got := w.GetPossibleModules(a, b, Right); len(got) != 2 {
+		t.Fatalf("before Forbid: got %d modules, want 2", len(got))
+	}
+
+	Forbid(m0, m1, Right)
+	got := w.GetPossibleModules(a, b, Right)
+	if len(got) != 1 || got[0] != m0 {
+		t.Errorf("after Forbid: got %v, want only module 0", got)
+	}
+
+	table := w.CompatibilityTable()
+	table[0][Right][1], table[1][Left][0] = true, true
+	if err := w.SetCompatibilityTable(table); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.GetPossibleModules(a, b, Right); len(got) != 2 {
+		t.Errorf("after SetCompatibilityTable: got %d modules, want 2", len(got))
+	}
+}
+
+func TestCustomIsPossibleFnSkipsCache(t *testing.T) {
+	w := NewSymbolic(2, func(a, b int, d Direction) bool { return true }, 2, 1)
+	calls := 0
+	w.IsPossibleFn = func(m *Module, from, to *Slot, d Direction) bool {
+		calls++
+		return DefaultIsPossibleFunc(m, from, to, d)
+	}
+	w.Initialize(1)
+	a, b := w.GetSlot(0, 0), w.GetSlot(1, 0)
+
+	w.GetPossibleModules(a, b, Right)
+	w.GetPossibleModules(a, b, Right)
+	if calls != 4 {
+		t.Errorf("IsPossibleFn called %d times, want 4", calls)
+	}
+}
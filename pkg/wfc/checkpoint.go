@@ -0,0 +1,78 @@
+package wfc
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+var (
+	ErrUnknownCheckpoint  = errors.New("no checkpoint with that name")
+	ErrCheckpointMismatch = errors.New("checkpoint doesn't match the size of the grid")
+)
+
+// checkpoint is the saved state of a wave, see Checkpoint.
+type checkpoint struct {
+	width       int
+	height      int
+	state       [][]*Module
+	collapsedAt []int
+	steps       int
+	decisions   []image.Point
+}
+
+// Checkpoint saves the current state of the wave under the given name,
+// replacing any previous checkpoint with that name. The superposition of
+// every slot is copied, so later changes don't affect the checkpoint. Use
+// Restore to go back to it, e.g. to undo an experiment in an editor.
+//
+// Checkpoints are cleared by Initialize.
+func (w *Wave) Checkpoint(name string) error {
+	if err := w.checkInitialized(); err != nil {
+		return err
+	}
+
+	c := &checkpoint{
+		width:       w.Width,
+		height:      w.Height,
+		state:       w.snapshot(),
+		collapsedAt: make([]int, len(w.PossibilitySpace)),
+		steps:       w.steps,
+		decisions:   w.DecisionLog(),
+	}
+	for i, s := range w.PossibilitySpace {
+		c.collapsedAt[i] = s.CollapsedAt
+	}
+
+	if w.checkpoints == nil {
+		w.checkpoints = make(map[string]*checkpoint)
+	}
+	w.checkpoints[name] = c
+	return nil
+}
+
+// Restore sets the wave back to the state saved with Checkpoint under the given
+// name. The checkpoint is kept, so it can be restored again.
+//
+// Returns ErrUnknownCheckpoint if there is no checkpoint with that name, and
+// ErrCheckpointMismatch if the grid changed its size since it was saved, e.g.
+// with RotateOutput.
+func (w *Wave) Restore(name string) error {
+	c, ok := w.checkpoints[name]
+	if !ok {
+		return fmt.Errorf("%q: %w", name, ErrUnknownCheckpoint)
+	}
+	if c.width != w.Width || c.height != w.Height || len(c.state) != len(w.PossibilitySpace) {
+		return fmt.Errorf("%q was saved for a %dx%d grid: %w", name, c.width, c.height, ErrCheckpointMismatch)
+	}
+
+	w.restore(c.state)
+	for i, s := range w.PossibilitySpace {
+		s.CollapsedAt = c.collapsedAt[i]
+	}
+	w.steps = c.steps
+	w.decisions = make([]image.Point, len(c.decisions))
+	copy(w.decisions, c.decisions)
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package wfc
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidCompatibilityTable = errors.New("invalid compatibility table")
+)
+
+// CompatibilityTable holds, for every pair of input modules and direction,
+// whether they may be placed next to each other: table[a][d][b] is true if
+// module "b" may be placed next to module "a" in direction d. Indices refer to
+// positions in Wave.Input. It is the dense form of AdjacencyGraph.
+type CompatibilityTable [][4][]bool
+
+// CompatibilityTable builds the compatibility table of the input modules from
+// their adjacency constraints, the rules added with Forbid and Allow and the
+// rules of the wave (see CanNeighbor), e.g. for a rule editor. The table is a
+// copy, changing it has no effect until it is applied with
+// SetCompatibilityTable.
+func (w *Wave) CompatibilityTable() CompatibilityTable {
+	table := make(CompatibilityTable, len(w.Input))
+	for i, a := range w.Input {
+		for _, d := range Directions {
+			table[i][d] = make([]bool, len(w.Input))
+			for j, b := range w.Input {
+				table[i][d][j] = w.CanNeighbor(a, b, d)
+			}
+		}
+	}
+	return table
+}
+
+// SetCompatibilityTable changes the rules of the wave to match the given table.
+// The pairings that differ from the current rules are recorded on the wave
+// (see CanNeighbor); the input modules are not changed, so other waves sharing
+// them keep their rules. The table must have an entry for every input module,
+// and table[a][d][b] must equal table[b][d.Opposite()][a], otherwise
+// ErrInvalidCompatibilityTable is returned before anything is changed.
+//
+// The new rules apply to the propagation from then on, but slots that have
+// already been constrained keep their superposition. Call Initialize again to
+// apply them to the whole wave.
+func (w *Wave) SetCompatibilityTable(table CompatibilityTable) error {
+	n := len(w.Input)
+	if len(table) != n {
+		return fmt.Errorf("table has %d modules, expected %d: %w",
+			len(table), n, ErrInvalidCompatibilityTable)
+	}
+	for i := range table {
+		for _, d := range Directions {
+			if len(table[i][d]) != n {
+				return fmt.Errorf("module %d, direction %s: "+
+					"table has %d entries, expected %d: %w",
+					i, d.ToString(), len(table[i][d]), n, ErrInvalidCompatibilityTable)
+			}
+		}
+	}
+	for i := range table {
+		for _, d := range Directions {
+			for j, ok := range table[i][d] {
+				if ok != table[j][d.Opposite()][i] {
+					return fmt.Errorf("modules %d and %d, direction %s: "+
+						"table is not symmetric: %w",
+						i, j, d.ToString(), ErrInvalidCompatibilityTable)
+				}
+			}
+		}
+	}
+
+	for i, a := range w.Input {
+		for _, d := range Directions {
+			for j, b := range w.Input {
+				if want := table[i][d][j]; want != w.CanNeighbor(a, b, d) {
+					w.setPairRule(a, b, d, want)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package wfc
+
+import "testing"
+
+func TestSetCompatibilityTableIsPerWave(t *testing.T) {
+	w := NewSymbolic(2, func(a, b int, d Direction) bool { return a == b }, 2, 2)
+	other := NewFromModules(w.Input, 2, 2)
+	m0, m1 := w.Input[0], w.Input[1]
+
+	table := w.CompatibilityTable()
+	table[0][Right][1], table[1][Left][0] = true, true // Add a pairing
+	table[0][Down][0], table[0][Up][0] = false, false  // Remove one
+	if err := w.SetCompatibilityTable(table); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		a, b      *Module
+		d         Direction
+		wave, mod bool
+	}{
+		{m0, m1, Right, true, false},
+		{m1, m0, Left, true, false},
+		{m0, m0, Down, false, true},
+		{m1, m1, Down, true, true},
+	}
+	for _, tt := range tests {
+		if got := w.CanNeighbor(tt.a, tt.b, tt.d); got != tt.wave {
+			t.Errorf("wave: %d next to %d (%s) = %v, want %v", tt.a.Index, tt.b.Index, tt.d.ToString(), got, tt.wave)
+		}
+		if got := tt.a.CanNeighbor(tt.b, tt.d); got != tt.mod {
+			t.Errorf("module: %d next to %d (%s) = %v, want %v", tt.a.Index, tt.b.Index, tt.d.ToString(), got, tt.mod)
+		}
+		if got := other.CanNeighbor(tt.a, tt.b, tt.d); got != tt.mod {
+			t.Errorf("other wave: %d next to %d (%s) = %v, want %v", tt.a.Index, tt.b.Index, tt.d.ToString(), got, tt.mod)
+		}
+	}
+
+	if got := w.CompatibilityTable(); got[0][Right][1] != true || got[0][Down][0] != false {
+		t.Error("CompatibilityTable doesn't reflect the table that was set")
+	}
+}
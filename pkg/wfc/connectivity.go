@@ -0,0 +1,86 @@
+package wfc
+
+import (
+	"errors"
+	"image"
+)
+
+var (
+	ErrNotConnected = errors.New("walkable tiles are not connected")
+)
+
+// CheckConnectivity checks that the slots collapsed into any of the walkable
+// modules form a single connected region, e.g. the floor of a level without
+// isolated rooms. Slots are connected to their four neighbors, across the
+// edges of the grid if it wraps around.
+//
+// The walkable slots are grouped into connected regions with a flood fill and
+// the coordinates of every walkable slot outside of the largest region are
+// returned. The result is empty if the walkable slots are fully connected.
+func (w *Wave) CheckConnectivity(walkable []*Module) []image.Point {
+	isWalkable := make(map[*Module]bool, len(walkable))
+	for _, m := range walkable {
+		isWalkable[m] = true
+	}
+	walk := func(s *Slot) bool {
+		return len(s.Superposition) == 1 && isWalkable[s.Superposition[0]]
+	}
+
+	visited := make(map[*Slot]bool)
+	regions := make([][]image.Point, 0)
+	largest := -1
+	for _, start := range w.PossibilitySpace {
+		if visited[start] || !walk(start) {
+			continue
+		}
+
+		region := make([]image.Point, 0)
+		visited[start] = true
+		queue := []*Slot{start}
+		for len(queue) > 0 {
+			s := queue[0]
+			queue = queue[1:]
+			region = append(region, image.Pt(s.X, s.Y))
+
+			for _, d := range Directions {
+				if !w.HasNeighbor(s, d) {
+					continue
+				}
+				n := w.GetNeighbor(s, d)
+				if !visited[n] && walk(n) {
+					visited[n] = true
+					queue = append(queue, n)
+				}
+			}
+		}
+
+		regions = append(regions, region)
+		if largest < 0 || len(region) > len(regions[largest]) {
+			largest = len(regions) - 1
+		}
+	}
+
+	res := make([]image.Point, 0)
+	for i, region := range regions {
+		if i != largest {
+			res = append(res, region...)
+		}
+	}
+	return res
+}
+
+// CollapseConnected collapses the wave with CollapseAuto until the walkable
+// modules form a single connected region (see CheckConnectivity). Like
+// CollapseUntilDiverse, the wave is reset between attempts.
+//
+// Returns ErrNotConnected if no attempt was connected, in which case the wave
+// holds the output of the last attempt.
+func (w *Wave) CollapseConnected(walkable []*Module, maxAttempts int) error {
+	ok, err := w.collapseUntil(maxAttempts, func() bool {
+		return len(w.CheckConnectivity(walkable)) == 0
+	})
+	if err == nil && !ok {
+		err = ErrNotConnected
+	}
+	return err
+}
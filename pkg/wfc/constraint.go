@@ -1,10 +1,12 @@
 package wfc
 
 import (
-	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"image"
+	"image/color"
 )
 
 // Adjacency constraint type.
@@ -21,7 +23,7 @@ type ConstraintId [8]byte
 
 // Equal returns true if the two adjacency constraints are equal.
 func (c ConstraintId) Equal(o ConstraintId) bool {
-	return bytes.Equal(c[:], o[:])
+	return c == o
 }
 
 // ConstraintFunc is a function that returns an adjacency hash for an image tile
@@ -45,7 +47,6 @@ func GetConstraintFunc(count int) ConstraintFunc {
 		u := w / count
 		v := h / count
 
-		var hash string
 		points := make([]Color, count)
 
 		for i := 0; i < count; i++ {
@@ -61,21 +62,169 @@ func GetConstraintFunc(count int) ConstraintFunc {
 			}
 		}
 
-		// Generate a hash from the colors
-		hash = ""
-		for _, c := range points {
-			hash += HexFromColor(c)
+		return hashColors(points)
+	}
+}
+
+// NewSampledConstraintFunc returns a constraint function that samples the
+// colors at the given positions along each edge. Positions are fractions of
+// the edge length, from 0 (top or left end) to 1 (bottom or right end).
+//
+// For example []float64{0.5} only compares the midpoint of each edge, which
+// is useful for tiles where the corners are decorative and shouldn't gate
+// adjacency.
+func NewSampledConstraintFunc(positions []float64) ConstraintFunc {
+	return func(img image.Image, dr Direction) ConstraintId {
+		w := img.Bounds().Max.X
+		h := img.Bounds().Max.Y
+
+		points := make([]Color, len(positions))
+		for i, p := range positions {
+			if p < 0 {
+				p = 0
+			}
+			if p > 1 {
+				p = 1
+			}
+			x := int(p * float64(w-1))
+			y := int(p * float64(h-1))
+
+			switch dr {
+			case Up:
+				points[i] = GetColor(img, x, 0)
+			case Down:
+				points[i] = GetColor(img, x, h-1)
+			case Left:
+				points[i] = GetColor(img, 0, y)
+			case Right:
+				points[i] = GetColor(img, w-1, y)
+			}
+		}
+
+		return hashColors(points)
+	}
+}
+
+// NewFullEdgeConstraintFunc returns a constraint function that hashes every
+// pixel along each edge instead of sampling a few of them. Two edges only
+// match if they are identical pixel for pixel.
+//
+// This is slower than sampling but exact, which makes it the right choice for
+// pixel-perfect tilesets and thin connector tiles where sampling may miss the
+// actual connection point.
+func NewFullEdgeConstraintFunc() ConstraintFunc {
+	return func(img image.Image, dr Direction) ConstraintId {
+		b := img.Bounds()
+
+		var points []Color
+		switch dr {
+		case Up, Down:
+			y := b.Min.Y
+			if dr == Down {
+				y = b.Max.Y - 1
+			}
+			for x := b.Min.X; x < b.Max.X; x++ {
+				points = append(points, GetColor(img, x, y))
+			}
+		case Left, Right:
+			x := b.Min.X
+			if dr == Right {
+				x = b.Max.X - 1
+			}
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				points = append(points, GetColor(img, x, y))
+			}
 		}
 
-		sum := sha256.Sum256([]byte(hash))
-		res := fmt.Sprintf("%x", sum)[:8]
+		return hashColors(points)
+	}
+}
+
+// NewHashedConstraintFunc returns a constraint function that reduces every
+// pixel along each edge to a 64 bit FNV-1a hash, stored in the constraint id.
+// Like NewFullEdgeConstraintFunc, two edges only match if they are identical
+// pixel for pixel, but hashing is much cheaper for large tiles. The hash is
+// computed once per edge when the modules are created, afterwards comparing
+// two edges is a single integer comparison.
+func NewHashedConstraintFunc() ConstraintFunc {
+	return func(img image.Image, dr Direction) ConstraintId {
+		b := img.Bounds()
+
+		x0, y0, dx, dy, n := b.Min.X, b.Min.Y, 1, 0, b.Dx()
+		switch dr {
+		case Down:
+			y0 = b.Max.Y - 1
+		case Left:
+			dx, dy, n = 0, 1, b.Dy()
+		case Right:
+			x0 = b.Max.X - 1
+			dx, dy, n = 0, 1, b.Dy()
+		}
+
+		h := fnv.New64a()
+		for i := 0; i < n; i++ {
+			c := GetColor(img, x0+i*dx, y0+i*dy)
+			h.Write(c[:])
+		}
 
 		var id ConstraintId
-		copy(id[:], res)
+		binary.BigEndian.PutUint64(id[:], h.Sum64())
 		return id
 	}
 }
 
+// NewInsetConstraintFunc returns a constraint function that ignores a frame of
+// inset pixels around each tile, for tiles with decorative borders that would
+// otherwise never match. The edges of the remaining inner area are passed on
+// to fn, so it composes with the other constraint functions, e.g.
+//
+//	NewInsetConstraintFunc(2, GetConstraintFunc(5))
+//
+// A nil fn uses DefaultConstraintFunc. If the inset leaves nothing of a tile,
+// the whole tile is used instead.
+func NewInsetConstraintFunc(inset int, fn ConstraintFunc) ConstraintFunc {
+	if fn == nil {
+		fn = DefaultConstraintFunc
+	}
+	return func(img image.Image, dr Direction) ConstraintId {
+		r := img.Bounds().Inset(inset)
+		if inset <= 0 || r.Empty() {
+			return fn(img, dr)
+		}
+		return fn(insetImage{img, r}, dr)
+	}
+}
+
+// insetImage is the area r of an image, moved to the origin. The constraint
+// functions assume tiles start at (0, 0), so a plain sub-image won't do.
+type insetImage struct {
+	image.Image
+	r image.Rectangle
+}
+
+func (i insetImage) Bounds() image.Rectangle {
+	return image.Rect(0, 0, i.r.Dx(), i.r.Dy())
+}
+
+func (i insetImage) At(x, y int) color.Color {
+	return i.Image.At(i.r.Min.X+x, i.r.Min.Y+y)
+}
+
+// hashColors generates an adjacency constraint id from a list of colors.
+func hashColors(points []Color) ConstraintId {
+	hash := ""
+	for _, c := range points {
+		hash += HexFromColor(c)
+	}
+
+	sum := sha256.Sum256([]byte(hash))
+	res := fmt.Sprintf("%x", sum)[:8]
+
+	var id ConstraintId
+	copy(id[:], res)
+	return id
+}
+
 // GetConstraintFromHex returns the adjacency constraint id for the given hex
 func GetConstraintFromHex(s string) ConstraintId {
 	var id ConstraintId
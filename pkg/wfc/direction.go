@@ -29,6 +29,34 @@ func (d Direction) Opposite() Direction {
 	return 0
 }
 
+// Offset returns the grid offset (dx, dy) of a single step in "this"
+// direction. The y axis grows downwards, so Up is (0, -1).
+func (d Direction) Offset() (int, int) {
+	switch d {
+	case Up:
+		return 0, -1
+	case Down:
+		return 0, 1
+	case Left:
+		return -1, 0
+	case Right:
+		return 1, 0
+	}
+	return 0, 0
+}
+
+// Opposite returns the opposite of the given direction. It is a function form
+// of Direction.Opposite for use in custom constraint and topology code.
+func Opposite(d Direction) Direction {
+	return d.Opposite()
+}
+
+// Offset returns the grid offset (dx, dy) of a single step in the given
+// direction. It is a function form of Direction.Offset.
+func Offset(d Direction) (int, int) {
+	return d.Offset()
+}
+
 // ToString returns the string representation of the direction.
 func (d Direction) ToString() string {
 	switch d {
@@ -0,0 +1,135 @@
+package wfc
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrNotDiverse = errors.New("output is not diverse enough")
+	ErrNotNovel   = errors.New("output is too similar to a previous one")
+)
+
+// DiversityScore measures how evenly the modules are used in the collapsed
+// slots. It is the Shannon entropy of the module counts (see ExportIndices),
+// normalized by the highest possible entropy for the input, so 0 means a
+// single module was used everywhere and 1 means every module was used equally
+// often. Uncollapsed slots are ignored.
+func (w *Wave) DiversityScore() float64 {
+	if len(w.Input) < 2 {
+		return 0
+	}
+
+	counts := make(map[int]int)
+	total := 0
+	for _, row := range w.ExportIndices() {
+		for _, idx := range row {
+			if idx >= 0 {
+				counts[idx]++
+				total++
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(total)
+		entropy -= p * math.Log(p)
+	}
+
+	return entropy / math.Log(float64(len(w.Input)))
+}
+
+// CollapseUntilDiverse collapses the wave with CollapseAuto until the output
+// has a DiversityScore of at least minScore, e.g. to discard outputs that are
+// mostly made of one tile. Between attempts the wave is reset to the state it
+// had when CollapseUntilDiverse was called. The random source is not reset, so
+// every attempt produces a different output.
+//
+// Returns ErrNotDiverse if no attempt reached the score, in which case the wave
+// holds the output of the last attempt.
+func (w *Wave) CollapseUntilDiverse(minScore float64, maxAttempts int) error {
+	ok, err := w.collapseUntil(maxAttempts, func() bool {
+		return w.DiversityScore() >= minScore
+	})
+	if err == nil && !ok {
+		err = ErrNotDiverse
+	}
+	return err
+}
+
+// CollapseNovel collapses the wave with CollapseAuto until the output differs
+// from every previous output by at least minDiff, e.g. to generate a gallery of
+// distinct maps. The difference of two outputs is the fraction of slots whose
+// modules differ (see DiffImage), from 0 for identical outputs to 1 when no
+// slot matches; previous waves of another size count as 1. Between attempts
+// the wave is reset to the state it had when CollapseNovel was called.
+//
+// Returns ErrNotNovel if no attempt was different enough, in which case the
+// wave holds the output of the last attempt.
+func (w *Wave) CollapseNovel(previous []*Wave, minDiff float64, maxAttempts int) error {
+	ok, err := w.collapseUntil(maxAttempts, func() bool {
+		for _, p := range previous {
+			if w.difference(p) < minDiff {
+				return false
+			}
+		}
+		return true
+	})
+	if err == nil && !ok {
+		err = ErrNotNovel
+	}
+	return err
+}
+
+// difference returns the fraction of slots whose module differs from the same
+// slot of the other wave, see CollapseNovel.
+func (w *Wave) difference(other *Wave) float64 {
+	if w.Width != other.Width || w.Height != other.Height || w.Width*w.Height == 0 {
+		return 1
+	}
+
+	a, b := w.ExportIndices(), other.ExportIndices()
+	diff := 0
+	for y := range a {
+		for x := range a[y] {
+			if a[y][x] != b[y][x] {
+				diff++
+			}
+		}
+	}
+	return float64(diff) / float64(w.Width*w.Height)
+}
+
+// collapseUntil collapses the wave with CollapseAuto until the output is
+// accepted, up to maxAttempts times. Between attempts the wave is reset to the
+// state it had when collapseUntil was called. Returns false if no attempt was
+// accepted.
+func (w *Wave) collapseUntil(maxAttempts int, accept func() bool) (bool, error) {
+	if err := w.checkInitialized(); err != nil {
+		return false, err
+	}
+
+	start := w.snapshot()
+	steps, log := w.steps, len(w.decisions)
+
+	for i := 0; i < maxAttempts; i++ {
+		if i > 0 {
+			w.restore(start)
+			w.steps = steps
+			w.decisions = w.decisions[:log]
+		}
+
+		if err := w.CollapseAuto(); err != nil {
+			return false, err
+		}
+		if accept() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,229 @@
+package wfc
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// TieBreakPolicy decides which slot SelectionStrategyLowestEntropy picks when
+// several slots share the lowest entropy and importance.
+type TieBreakPolicy int
+
+const (
+	// TieBreakRandom picks any of the tied slots at random. This is the
+	// default.
+	TieBreakRandom TieBreakPolicy = iota
+
+	// TieBreakNearest picks the tied slot closest (by Manhattan distance) to
+	// a collapsed slot, which grows the output contiguously.
+	TieBreakNearest
+
+	// TieBreakFarthest picks the tied slot farthest from any collapsed slot,
+	// which spreads the decisions over the grid.
+	TieBreakFarthest
+)
+
+// entropyEntry is a cached entropy value for a slot. The entropy of a slot is
+// the number of modules left in its superposition.
+type entropyEntry struct {
+	slot       *Slot
+	entropy    int
+	score      float64 // Entropy plus the noise scaled by EntropyNoise, lower wins
+	importance float64 // Importance of the slot, higher values win ties
+	noise      float64 // Random tie-breaker drawn from the wave's random source
+	order      int     // Position of the slot in the grid, used as a last resort
+}
+
+// entropyHeap is a min-heap of slots ordered by their cached entropy.
+//
+// Entries are never removed when a slot changes. Instead, a new entry is pushed
+// and the outdated one is skipped once it reaches the top of the heap. This
+// keeps updates during propagation at O(log n).
+type entropyHeap []entropyEntry
+
+func (h entropyHeap) Len() int { return len(h) }
+
+func (h entropyHeap) Less(i, j int) bool {
+	if h[i].score != h[j].score {
+		return h[i].score < h[j].score
+	}
+	if h[i].importance != h[j].importance {
+		return h[i].importance > h[j].importance
+	}
+	if h[i].noise != h[j].noise {
+		return h[i].noise < h[j].noise
+	}
+	return h[i].order < h[j].order
+}
+
+func (h entropyHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *entropyHeap) Push(x interface{}) { *h = append(*h, x.(entropyEntry)) }
+
+func (h *entropyHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// newEntropyHeap builds a heap holding the current entropy of every slot.
+func (w *Wave) newEntropyHeap() *entropyHeap {
+	h := make(entropyHeap, 0, len(w.PossibilitySpace))
+	for _, s := range w.PossibilitySpace {
+		h = append(h, w.entropyEntryOf(s))
+	}
+	heap.Init(&h)
+	return &h
+}
+
+// entropyEntryOf returns a heap entry holding the current entropy of a slot.
+func (w *Wave) entropyEntryOf(s *Slot) entropyEntry {
+	e := entropyEntry{
+		slot:    s,
+		entropy: len(s.Superposition),
+		noise:   w.rng.Float64(),
+		order:   s.X + s.Y*w.Width,
+	}
+	e.score = w.entropyScore(e)
+	if w.importance != nil {
+		e.importance = w.importance[s.Y][s.X]
+	}
+	return e
+}
+
+// entropyScore returns the entropy of an entry with its noise added, scaled by
+// EntropyNoise.
+func (w *Wave) entropyScore(e entropyEntry) float64 {
+	return float64(e.entropy) + w.EntropyNoise*e.noise
+}
+
+// updateEntropy records the new entropy of a slot after its superposition
+// changed. It is a no-op until the heap has been built.
+func (w *Wave) updateEntropy(s *Slot) {
+	if w.entropy == nil || len(s.Superposition) <= 1 {
+		return
+	}
+	heap.Push(w.entropy, w.entropyEntryOf(s))
+}
+
+// SetImportance assigns an importance to every slot, indexed [y][x]. When
+// using SelectionStrategyLowestEntropy, ties between slots of equal entropy go
+// to the slot with the higher importance, so e.g. the center of the screen can
+// be made to settle first. Pass nil to clear it.
+func (w *Wave) SetImportance(importance [][]float64) error {
+	if importance != nil {
+		if len(importance) != w.Height {
+			return fmt.Errorf("importance map has %d rows, expected %d", len(importance), w.Height)
+		}
+		for y, row := range importance {
+			if len(row) != w.Width {
+				return fmt.Errorf("importance map row %d has %d columns, expected %d", y, len(row), w.Width)
+			}
+		}
+	}
+
+	w.importance = importance
+	w.entropy = nil
+	return nil
+}
+
+// lowestEntropySlot returns the uncollapsed slot with the lowest entropy, or
+// nil if every slot is collapsed. Ties are broken by importance, then according
+// to the TieBreak policy, then at random using the wave's random source, so the
+// choice is reproducible for a seed. With EntropyNoise the noisy score decides
+// and the TieBreak policy is ignored.
+//
+// The heap is built lazily on first use so that changes made to the
+// possibility space after Initialize are picked up.
+func (w *Wave) lowestEntropySlot() *Slot {
+	if w.entropy == nil {
+		w.entropy = w.newEntropyHeap()
+	}
+
+	for w.entropy.Len() > 0 {
+		e := heap.Pop(w.entropy).(entropyEntry)
+		n := len(e.slot.Superposition)
+		if n <= 1 {
+			// Collapsed or contradiction, nothing to do here
+			continue
+		}
+		if n != e.entropy {
+			// Outdated entry, re-queue it with the actual entropy
+			e.entropy = n
+			e.score = w.entropyScore(e)
+			heap.Push(w.entropy, e)
+			continue
+		}
+		if w.TieBreak != TieBreakRandom && w.EntropyNoise <= 0 {
+			// The entry stays valid unless its slot is picked
+			heap.Push(w.entropy, e)
+			return w.tieBreakSlot(e.entropy, e.importance)
+		}
+		return e.slot
+	}
+
+	return nil
+}
+
+// tieBreakSlot returns the slot with the given entropy and importance that is
+// nearest to or farthest from the collapsed slots, according to the TieBreak
+// policy. Remaining ties are broken at random.
+func (w *Wave) tieBreakSlot(entropy int, importance float64) *Slot {
+	dist := w.collapsedDistances()
+	best := make([]*Slot, 0)
+	bestDist := 0
+	for _, s := range w.PossibilitySpace {
+		if len(s.Superposition) != entropy {
+			continue
+		}
+		if w.importance != nil && w.importance[s.Y][s.X] != importance {
+			continue
+		}
+
+		d := dist[s]
+		if w.TieBreak == TieBreakFarthest {
+			d = -d
+		}
+		if len(best) == 0 || d < bestDist {
+			best, bestDist = best[:0], d
+		}
+		if d == bestDist {
+			best = append(best, s)
+		}
+	}
+	return best[w.rng.Intn(len(best))]
+}
+
+// collapsedDistances returns the Manhattan distance of every slot to the
+// nearest collapsed slot, found with a breadth-first search starting from every
+// collapsed slot at once. Slots that can't be reached, e.g. because nothing has
+// been collapsed yet, are missing from the map and count as distance 0.
+func (w *Wave) collapsedDistances() map[*Slot]int {
+	dist := make(map[*Slot]int)
+	queue := make([]*Slot, 0)
+	for _, s := range w.PossibilitySpace {
+		if len(s.Superposition) == 1 {
+			dist[s] = 0
+			queue = append(queue, s)
+		}
+	}
+
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, d := range Directions {
+			if !w.HasNeighbor(s, d) {
+				continue
+			}
+			n := w.GetNeighbor(s, d)
+			if _, ok := dist[n]; ok {
+				continue
+			}
+			dist[n] = dist[s] + 1
+			queue = append(queue, n)
+		}
+	}
+
+	return dist
+}
@@ -0,0 +1,29 @@
+package wfc
+
+import "testing"
+
+// firstPick returns the slot picked first by lowest-entropy selection on a
+// fresh wave, where every slot ties with every other.
+func firstPick(seed int, policy TieBreakPolicy) (int, int) {
+	w := NewSymbolic(3, func(a, b int, d Direction) bool { return true }, 8, 8)
+	w.TieBreak = policy
+	w.Initialize(seed)
+	s := w.lowestEntropySlot()
+	return s.X, s.Y
+}
+
+func TestTieBreakSeeds(t *testing.T) {
+	for _, policy := range []TieBreakPolicy{TieBreakRandom, TieBreakNearest} {
+		picks := make(map[[2]int]bool)
+		for seed := 1; seed <= 10; seed++ {
+			x, y := firstPick(seed, policy)
+			if rx, ry := firstPick(seed, policy); rx != x || ry != y {
+				t.Errorf("policy %d, seed %d: picked %d,%d then %d,%d", policy, seed, x, y, rx, ry)
+			}
+			picks[[2]int{x, y}] = true
+		}
+		if len(picks) < 2 {
+			t.Errorf("policy %d: every seed picked the same tied slot", policy)
+		}
+	}
+}
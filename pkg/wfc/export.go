@@ -0,0 +1,432 @@
+package wfc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ExportIndices returns the index of the module each slot collapsed into, as
+// rows of the grid (indexed [y][x]). Slots that are not collapsed, or that
+// are in a contradiction state, are set to -1.
+func (w *Wave) ExportIndices() [][]int {
+	grid := make([][]int, w.Height)
+	for y := 0; y < w.Height; y++ {
+		grid[y] = make([]int, w.Width)
+		for x := 0; x < w.Width; x++ {
+			s := w.GetSlot(x, y)
+			if s != nil && len(s.Superposition) == 1 {
+				grid[y][x] = s.Superposition[0].Index
+			} else {
+				grid[y][x] = -1
+			}
+		}
+	}
+	return grid
+}
+
+// ExportGoLiteral returns the module index grid (see ExportIndices) as a
+// gofmt-formatted Go variable declaration, e.g. to capture a known-good
+// generation as a test fixture.
+func (w *Wave) ExportGoLiteral(varName string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "var %s = [][]int{\n", varName)
+	for _, row := range w.ExportIndices() {
+		cells := make([]string, len(row))
+		for i, idx := range row {
+			cells[i] = fmt.Sprint(idx)
+		}
+		fmt.Fprintf(&b, "\t{%s},\n", strings.Join(cells, ", "))
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// ExportPaletted renders the wave like ExportImage, but into an indexed image
+// using a palette built from the colors of the rendered image. Tile-based
+// output uses few colors, so this yields much smaller PNG files. Since the
+// palette comes from the rendered pixels, it includes the colors of grid
+// lines, contradiction markers and transparent tile overlays.
+//
+// If the image uses more than 256 colors altogether, the web-safe palette is
+// used instead and colors are mapped to their closest match.
+func (w *Wave) ExportPaletted() *image.Paletted {
+	img := w.ExportImage()
+
+	seen := map[color.RGBA]bool{}
+	pal := color.Palette{}
+	add := func(c color.Color) {
+		rgba := color.RGBAModel.Convert(c).(color.RGBA)
+		if !seen[rgba] {
+			seen[rgba] = true
+			pal = append(pal, rgba)
+		}
+	}
+
+	// Keep transparent at index 0, like the web-safe fallback
+	add(color.RGBA{})
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y && len(pal) <= 256; y++ {
+		for x := b.Min.X; x < b.Max.X && len(pal) <= 256; x++ {
+			add(img.At(x, y))
+		}
+	}
+
+	if len(pal) > 256 {
+		pal = append(color.Palette{color.RGBA{}}, palette.WebSafe...)
+	}
+
+	out := image.NewPaletted(img.Bounds(), pal)
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	return out
+}
+
+// ExportFrames collapses the wave one Step at a time and saves every N-th
+// intermediate state as a numbered PNG file in the given directory
+// (frame-00000.png, frame-00001.png, ...), e.g. to build a video with ffmpeg:
+//
+//	ffmpeg -i frame-%05d.png output.mp4
+//
+// With FrameArrows set, every N-th propagation is saved instead, with an arrow
+// showing where the constraint flows, e.g. for a teaching animation. The final
+// state is always saved, including a failed one. Returns the error of the
+// collapse, or of writing a frame.
+func (w *Wave) ExportFrames(dir string, everyN int) error {
+	if everyN < 1 {
+		everyN = 1
+	}
+
+	frame := 0
+	save := func(img image.Image) error {
+		file := filepath.Join(dir, fmt.Sprintf("frame-%05d.png", frame))
+		frame++
+		return SaveImage(file, img)
+	}
+
+	var ferr error
+	if w.FrameArrows {
+		hook := w.OnPropagate
+		defer func() { w.OnPropagate = hook }()
+
+		n := 0
+		w.OnPropagate = func(from, to *Slot, d Direction) {
+			if hook != nil {
+				hook(from, to, d)
+			}
+			n++
+			if ferr == nil && n%everyN == 0 {
+				img := w.ExportImage().(*image.RGBA)
+				w.drawArrow(img, from, d)
+				ferr = save(img)
+			}
+		}
+	}
+
+	for step := 1; ; step++ {
+		done, err := w.Step()
+		if ferr != nil {
+			return ferr
+		}
+		if err != nil || done {
+			if serr := save(w.ExportImage()); serr != nil {
+				return serr
+			}
+			return err
+		}
+		if !w.FrameArrows && step%everyN == 0 {
+			if err := save(w.ExportImage()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// drawArrow draws a yellow arrow from the center of a slot towards its neighbor
+// in the given direction.
+func (w *Wave) drawArrow(img *image.RGBA, s *Slot, d Direction) {
+	u, v := w.tileSize()
+	cx, cy := s.X*u+u/2, s.Y*v+v/2
+	dx, dy := d.Offset()
+	length := min(u, v)/2 - 1
+	head := max(length/3, 1)
+	c := color.RGBA{255, 255, 0, 255}
+
+	for t := 0; t <= length; t++ {
+		img.Set(cx+dx*t, cy+dy*t, c)
+	}
+	for i := 1; i <= head; i++ {
+		bx, by := cx+dx*(length-i), cy+dy*(length-i)
+		for j := -i; j <= i; j++ {
+			img.Set(bx-dy*j, by+dx*j, c)
+		}
+	}
+}
+
+// ExportBlended renders the wave like ExportImage, but slots that aren't
+// collapsed yet show the average of their remaining candidate tiles, each
+// weighted 1/len of the superposition. A slot with many options looks blurry
+// and gets sharper as options are ruled out, which visualizes the
+// superposition. Contradictions are red. Candidates without an image are left
+// out of the average, slots without any candidate images are gray like in
+// ExportImage. Returns an empty image if the image would be too large, see
+// CheckExportSize.
+func (w *Wave) ExportBlended() image.Image {
+	u, v := w.tileSize()
+	img := image.NewRGBA(w.exportRect())
+	if img.Rect.Empty() {
+		return img
+	}
+
+	for _, s := range w.PossibilitySpace {
+		if len(s.Superposition) == 0 {
+			r := image.Rect(s.X*u, s.Y*v, (s.X+1)*u, (s.Y+1)*v)
+			draw.Draw(img, r, image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+			continue
+		}
+
+		n := uint32(0)
+		for _, m := range s.Superposition {
+			if m.Image != nil {
+				n++
+			}
+		}
+		if n == 0 {
+			r := image.Rect(s.X*u, s.Y*v, (s.X+1)*u, (s.Y+1)*v)
+			draw.Draw(img, r, image.NewUniform(placeholderColor), image.Point{}, draw.Src)
+			continue
+		}
+
+		for x := 0; x < u; x++ {
+			for y := 0; y < v; y++ {
+				var r, g, b, a uint32
+				for _, m := range s.Superposition {
+					if m.Image == nil {
+						continue
+					}
+					mb := m.Image.Bounds()
+					cr, cg, cb, ca := m.Image.At(mb.Min.X+x, mb.Min.Y+y).RGBA()
+					r, g, b, a = r+cr, g+cg, b+cb, a+ca
+				}
+				img.Set(s.X*u+x, s.Y*v+y, color.RGBA64{
+					uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n),
+				})
+			}
+		}
+	}
+
+	if w.GridLines > 0 {
+		w.drawGridLines(img, u, v)
+	}
+
+	return img
+}
+
+// Snapshot returns a copy of the grid of collapsed modules, indexed [y][x],
+// with nil for slots that aren't collapsed (or in a contradiction state).
+//
+// Snapshot is safe to call from another goroutine while the wave is being
+// collapsed with Step, Collapse, CollapseIter or All, e.g. to render the
+// progress without blocking the collapse for long. It must not be called
+// concurrently with anything else that changes the wave, such as Initialize or
+// CollapseAuto. The returned grid is owned by the caller; the modules in it
+// are shared with the wave and must not be modified.
+func (w *Wave) Snapshot() [][]*Module {
+	if w.mu != nil {
+		w.mu.RLock()
+		defer w.mu.RUnlock()
+	}
+
+	grid := make([][]*Module, w.Height)
+	for y := 0; y < w.Height; y++ {
+		grid[y] = make([]*Module, w.Width)
+	}
+	for _, s := range w.PossibilitySpace {
+		if len(s.Superposition) == 1 {
+			grid[s.Y][s.X] = s.Superposition[0]
+		}
+	}
+	return grid
+}
+
+// ExportLegend renders a legend of the modules used in the current output, to
+// share along with a generated map. Every module that some slot is collapsed
+// into gets a row with its tile image, its index and its tags (see
+// Module.Tags), in the order of the input. Text is drawn with a small built-in
+// font, in upper case.
+func (w *Wave) ExportLegend() image.Image {
+	const (
+		pad   = 4
+		scale = 2
+	)
+
+	used := make(map[*Module]bool)
+	for _, s := range w.PossibilitySpace {
+		if len(s.Superposition) == 1 {
+			used[s.Superposition[0]] = true
+		}
+	}
+	position := make(map[*Module]int, len(w.Input))
+	for i, m := range w.Input {
+		position[m] = i
+	}
+	modules := make([]*Module, 0, len(used))
+	for m := range used {
+		modules = append(modules, m)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return position[modules[i]] < position[modules[j]]
+	})
+
+	u, v := w.tileSize()
+	rowHeight := v
+	if th := glyphHeight * scale; th > rowHeight {
+		rowHeight = th
+	}
+
+	labels := make([]string, len(modules))
+	textW := 0
+	for i, m := range modules {
+		labels[i] = strings.TrimSpace(fmt.Sprintf("#%d %s", m.Index, strings.Join(m.Tags, ",")))
+		if tw := textWidth(labels[i], scale); tw > textW {
+			textW = tw
+		}
+	}
+
+	width := pad + u + pad + textW + pad
+	height := pad + len(modules)*(rowHeight+pad)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	for i, m := range modules {
+		y := pad + i*(rowHeight+pad)
+		if m.Image != nil {
+			r := image.Rect(pad, y, pad+u, y+v)
+			draw.Draw(img, r, m.Image, m.Image.Bounds().Min, draw.Over)
+		}
+		ty := y + (rowHeight-glyphHeight*scale)/2
+		drawText(img, image.Pt(pad+u+pad, ty), labels[i], color.Black, scale)
+	}
+
+	return img
+}
+
+// ExportSVG writes the wave as an SVG document with one element per slot,
+// which scales cleanly for print and presentations. Every module used is
+// embedded once as a base64 encoded PNG and referenced by the slots collapsed
+// into it. Uncollapsed slots are drawn as translucent gray rectangles and
+// contradictions as red ones. Modules without an image are drawn as gray
+// rectangles labeled with their index.
+func (w *Wave) ExportSVG(out io.Writer) error {
+	u, v := w.tileSize()
+	if u == 0 || v == 0 {
+		u, v = 16, 16
+	}
+
+	b := bufio.NewWriter(out)
+	fmt.Fprintf(b, "<svg xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\" "+
+		"width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" data-grid=\"%dx%d\">\n",
+		w.Width*u, w.Height*v, w.Width*u, w.Height*v, w.Width, w.Height)
+
+	// Embed every used module image once
+	ids := make(map[*Module]string)
+	fmt.Fprintln(b, "<defs>")
+	for i, m := range w.Input {
+		used := false
+		for _, s := range w.PossibilitySpace {
+			if len(s.Superposition) == 1 && s.Superposition[0] == m {
+				used = true
+				break
+			}
+		}
+		if !used || m.Image == nil {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, m.Image); err != nil {
+			return err
+		}
+		ids[m] = fmt.Sprintf("m%d", i)
+		fmt.Fprintf(b, "<image id=\"%s\" width=\"%d\" height=\"%d\" xlink:href=\"data:image/png;base64,%s\"/>\n",
+			ids[m], u, v, base64.StdEncoding.EncodeToString(buf.Bytes()))
+	}
+	fmt.Fprintln(b, "</defs>")
+
+	for _, s := range w.PossibilitySpace {
+		x, y := s.X*u, s.Y*v
+		switch len(s.Superposition) {
+		case 0:
+			fmt.Fprintf(b, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"red\"/>\n", x, y, u, v)
+		case 1:
+			m := s.Superposition[0]
+			if id, ok := ids[m]; ok {
+				fmt.Fprintf(b, "<use xlink:href=\"#%s\" x=\"%d\" y=\"%d\"/>\n", id, x, y)
+			} else {
+				fmt.Fprintf(b, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"gray\"/>\n", x, y, u, v)
+				fmt.Fprintf(b, "<text x=\"%d\" y=\"%d\" font-size=\"%d\" text-anchor=\"middle\" dominant-baseline=\"middle\">%d</text>\n",
+					x+u/2, y+v/2, v/2, m.Index)
+			}
+		default:
+			fmt.Fprintf(b, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"gray\" fill-opacity=\"0.3\"/>\n", x, y, u, v)
+		}
+	}
+
+	fmt.Fprintln(b, "</svg>")
+	return b.Flush()
+}
+
+// DiffImage renders the wave like ExportImage and highlights the slots whose
+// module differs from the same slot in the other wave, e.g. to see how a
+// different seed or weight changed the output. Slots that match are dimmed,
+// slots that differ keep their colors and get a red outline. Slots count as
+// different if one of them is collapsed and the other one isn't, or if they
+// are collapsed into modules with different indices (see ExportIndices).
+//
+// Returns nil if the waves don't have the same width and height.
+func (w *Wave) DiffImage(other *Wave) image.Image {
+	if w.Width != other.Width || w.Height != other.Height {
+		return nil
+	}
+
+	out := w.ExportImage()
+	img := image.NewRGBA(out.Bounds())
+	draw.Draw(img, img.Bounds(), out, image.Point{}, draw.Src)
+
+	u, v := w.tileSize()
+	dim := image.NewUniform(color.RGBA{0, 0, 0, 160})
+	outline := color.RGBA{255, 0, 0, 255}
+	a, b := w.ExportIndices(), other.ExportIndices()
+	for y := 0; y < w.Height; y++ {
+		for x := 0; x < w.Width; x++ {
+			r := image.Rect(x*u, y*v, (x+1)*u, (y+1)*v)
+			if a[y][x] == b[y][x] {
+				draw.Draw(img, r, dim, image.Point{}, draw.Over)
+				continue
+			}
+			for i := r.Min.X; i < r.Max.X; i++ {
+				img.Set(i, r.Min.Y, outline)
+				img.Set(i, r.Max.Y-1, outline)
+			}
+			for j := r.Min.Y; j < r.Max.Y; j++ {
+				img.Set(r.Min.X, j, outline)
+				img.Set(r.Max.X-1, j, outline)
+			}
+		}
+	}
+
+	return img
+}
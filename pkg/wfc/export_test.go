@@ -0,0 +1,45 @@
+package wfc
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestExportSymbolicModules(t *testing.T) {
+	tile := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i := range tile.Pix {
+		tile.Pix[i] = 255
+	}
+	w := NewFromModules([]*Module{{Index: 0, Image: tile}, {Index: 1}}, 2, 1)
+	w.Initialize(1)
+	w.GetSlot(0, 0).Superposition = []*Module{w.Input[0]}
+	w.GetSlot(1, 0).Superposition = []*Module{w.Input[1]}
+
+	tests := []struct {
+		name   string
+		export func() image.Image
+	}{
+		{"ExportImage", w.ExportImage},
+		{"ExportPaletted", func() image.Image { return w.ExportPaletted() }},
+		{"ExportBlended", w.ExportBlended},
+	}
+	for _, tt := range tests {
+		img := tt.export()
+		if got := color.RGBAModel.Convert(img.At(0, 0)); got != (color.RGBA{255, 255, 255, 255}) {
+			t.Errorf("%s: tile pixel = %v, want white", tt.name, got)
+		}
+		if got := color.RGBAModel.Convert(img.At(2, 0)); got != placeholderColor {
+			t.Errorf("%s: symbolic pixel = %v, want %v", tt.name, got, placeholderColor)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := w.ExportSVG(&buf); err != nil {
+		t.Fatalf("ExportSVG: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`fill="gray"`)) {
+		t.Error("ExportSVG: no placeholder for the symbolic module")
+	}
+}
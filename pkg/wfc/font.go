@@ -0,0 +1,69 @@
+package wfc
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// Size of the glyphs of the built-in font, in font pixels.
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+)
+
+// glyphs is a tiny 3x5 bitmap font used to label exported images. Every row is
+// a bitmask, the highest of the three bits being the leftmost pixel. Letters
+// are upper case only.
+var glyphs = map[rune][glyphHeight]uint8{
+	'0': {7, 5, 5, 5, 7}, '1': {2, 6, 2, 2, 7}, '2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 7, 1, 7}, '4': {5, 5, 7, 1, 1}, '5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7}, '7': {7, 1, 1, 1, 1}, '8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	'A': {2, 5, 7, 5, 5}, 'B': {6, 5, 6, 5, 6}, 'C': {3, 4, 4, 4, 3},
+	'D': {6, 5, 5, 5, 6}, 'E': {7, 4, 6, 4, 7}, 'F': {7, 4, 6, 4, 4},
+	'G': {3, 4, 5, 5, 3}, 'H': {5, 5, 7, 5, 5}, 'I': {7, 2, 2, 2, 7},
+	'J': {1, 1, 1, 5, 2}, 'K': {5, 5, 6, 5, 5}, 'L': {4, 4, 4, 4, 7},
+	'M': {5, 7, 7, 5, 5}, 'N': {6, 5, 5, 5, 5}, 'O': {2, 5, 5, 5, 2},
+	'P': {6, 5, 6, 4, 4}, 'Q': {2, 5, 5, 6, 3}, 'R': {6, 5, 6, 5, 5},
+	'S': {3, 4, 2, 1, 6}, 'T': {7, 2, 2, 2, 2}, 'U': {5, 5, 5, 5, 7},
+	'V': {5, 5, 5, 5, 2}, 'W': {5, 5, 7, 7, 5}, 'X': {5, 5, 2, 5, 5},
+	'Y': {5, 5, 2, 2, 2}, 'Z': {7, 1, 2, 4, 7},
+	' ': {0, 0, 0, 0, 0}, '-': {0, 0, 7, 0, 0}, '_': {0, 0, 0, 0, 7},
+	'.': {0, 0, 0, 0, 2}, ',': {0, 0, 0, 2, 4}, ':': {0, 2, 0, 2, 0},
+	'#': {5, 7, 5, 7, 5}, '?': {6, 1, 2, 0, 2},
+}
+
+// textWidth returns the width in pixels of a text drawn with drawText.
+func textWidth(text string, scale int) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return (n*(glyphWidth+1) - 1) * scale
+}
+
+// drawText draws a text with the built-in font, its top left corner at the
+// given point. Every font pixel is scale x scale image pixels. Characters
+// without a glyph are drawn as a question mark.
+func drawText(img *image.RGBA, p image.Point, text string, c color.Color, scale int) {
+	for i, r := range []rune(strings.ToUpper(text)) {
+		glyph, ok := glyphs[r]
+		if !ok {
+			glyph = glyphs['?']
+		}
+		x0 := p.X + i*(glyphWidth+1)*scale
+		for gy, bits := range glyph {
+			for gx := 0; gx < glyphWidth; gx++ {
+				if bits&(1<<(glyphWidth-1-gx)) == 0 {
+					continue
+				}
+				for dx := 0; dx < scale; dx++ {
+					for dy := 0; dy < scale; dy++ {
+						img.Set(x0+gx*scale+dx, p.Y+gy*scale+dy, c)
+					}
+				}
+			}
+		}
+	}
+}
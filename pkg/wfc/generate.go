@@ -0,0 +1,107 @@
+package wfc
+
+import (
+	"context"
+	"image"
+	"maps"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// GenerateResult is the outcome of collapsing a wave for a single seed.
+type GenerateResult struct {
+	Seed  int         // The seed the wave was initialized with
+	Image image.Image // The exported image, also set for failed collapses
+	Err   error       // The collapse error, nil on success
+}
+
+// GenerateStream collapses one copy of the wave per seed and sends the results
+// on the returned channel as soon as they complete, so results may arrive out
+// of order. The copies share the wave's input and settings and are collapsed
+// by a bounded pool of workers, one per CPU.
+//
+// Each copy is set up with Initialize. The channel is closed once every seed
+// has been processed or the context is cancelled.
+func (w *Wave) GenerateStream(ctx context.Context, seeds []int) <-chan GenerateResult {
+	results := make(chan GenerateResult)
+	jobs := make(chan int)
+
+	workers := runtime.NumCPU()
+	if workers > len(seeds) {
+		workers = len(seeds)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seed := range jobs {
+				wave := w.spawn()
+				wave.Initialize(seed)
+				err := wave.collapseAll()
+
+				res := GenerateResult{Seed: seed, Image: wave.ExportImage(), Err: err}
+				select {
+				case results <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, seed := range seeds {
+			select {
+			case jobs <- seed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// spawn returns an uninitialized copy of the wave sharing its input modules and
+// settings, but none of its collapse state. The rules and policies kept in maps
+// (see CanNeighbor, LockCollapsed, BorderOnly, ForbidPattern and the like) are
+// copied, so changing them on the copy doesn't affect the wave and copies can
+// be collapsed concurrently. The hooks are shared.
+func (w *Wave) spawn() *Wave {
+	c := *w
+	c.PossibilitySpace = nil
+	c.History = nil
+	c.rng = nil
+	c.entropy = nil
+	c.possible = nil
+	c.initial = nil
+	c.placed = nil
+	c.placedCount = nil
+	c.steps = 0
+	c.decisions = nil
+	c.patternQueue = nil
+	c.checkpoints = nil
+	c.mu = &sync.RWMutex{}
+	c.stats = Stats{}
+	c.entropyHistory = nil
+
+	c.DirectionalWeight = maps.Clone(w.DirectionalWeight)
+	c.borderOnly = maps.Clone(w.borderOnly)
+	c.interiorOnly = maps.Clone(w.interiorOnly)
+	c.patterns = slices.Clone(w.patterns)
+	c.multiTileParts = maps.Clone(w.multiTileParts)
+	c.tagAffinity = maps.Clone(w.tagAffinity)
+	c.weights = maps.Clone(w.weights)
+	c.pairRules = maps.Clone(w.pairRules)
+	c.locked = maps.Clone(w.locked)
+	return &c
+}
@@ -0,0 +1,44 @@
+package wfc
+
+import "testing"
+
+func TestSpawnCopiesPolicies(t *testing.T) {
+	w := NewSymbolic(2, func(a, b int, d Direction) bool { return true }, 3, 3)
+	m0, m1 := w.Input[0], w.Input[1]
+	w.BorderOnly(m0)
+	w.setPairRule(m0, m1, Right, false)
+
+	c := w.spawn()
+	c.InteriorOnly(m1)
+	c.BorderOnly(m1)
+	c.ForbidSquareBlocks(m0)
+	c.setPairRule(m0, m0, Up, false)
+
+	if !c.borderOnly[m0] || c.CanNeighbor(m0, m1, Right) {
+		t.Error("copy lost the rules of the wave")
+	}
+	if w.borderOnly[m1] || w.interiorOnly[m1] {
+		t.Error("placement policy of the copy leaked into the wave")
+	}
+	if len(w.patterns) != 0 {
+		t.Error("pattern of the copy leaked into the wave")
+	}
+	if !w.CanNeighbor(m0, m0, Up) {
+		t.Error("pairing rule of the copy leaked into the wave")
+	}
+}
+
+func TestSweepWithWaveRules(t *testing.T) {
+	w := NewSymbolic(3, func(a, b int, d Direction) bool { return true }, 6, 6)
+	w.setPairRule(w.Input[0], w.Input[1], Right, false)
+
+	results := w.Sweep([]ParamSet{{Seed: 1}, {Seed: 2}, {Seed: 3}, {Seed: 4}})
+	for _, r := range results {
+		if !r.Success {
+			t.Fatalf("seed %d: %v", r.Params.Seed, r.Err)
+		}
+		if c, found := r.Wave.leastImportantViolation(); found {
+			t.Errorf("seed %d violates the rules of the wave: %+v", r.Params.Seed, c)
+		}
+	}
+}
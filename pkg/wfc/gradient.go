@@ -0,0 +1,58 @@
+package wfc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EdgeLevelFunc returns the type and intensity of a module's edge in the given
+// direction, e.g. "grass" and 3 for a tile that is mostly grass on that side.
+type EdgeLevelFunc func(m *Module, d Direction) (kind string, level int)
+
+// ConnectEdgeLevels replaces the adjacency rules between the input modules by
+// a gradient model: two modules may be neighbors if their touching edges have
+// the same type and their levels differ by at most one (see EdgeLevelFunc).
+// This produces smooth transitions, e.g. between biomes, without needing an
+// exact edge match.
+//
+// The rules only apply to this wave (see CanNeighbor), the modules themselves
+// are left unchanged, so other waves sharing them keep their rules. Call this
+// after adding rotated or reflected variants and before Initialize.
+func (w *Wave) ConnectEdgeLevels(parse EdgeLevelFunc) {
+	type edge struct {
+		kind  string
+		level int
+	}
+	edges := make([][4]edge, len(w.Input))
+	for i, m := range w.Input {
+		for _, d := range Directions {
+			kind, level := parse(m, d)
+			edges[i][d] = edge{kind, level}
+		}
+	}
+
+	for i, a := range w.Input {
+		for j, b := range w.Input {
+			for _, d := range Directions {
+				ea, eb := edges[i][d], edges[j][d.Opposite()]
+				diff := ea.level - eb.level
+				w.setPairRule(a, b, d, ea.kind == eb.kind && diff >= -1 && diff <= 1)
+			}
+		}
+	}
+}
+
+// ParseEdgeLevel parses edge metadata in the form "type:level", e.g.
+// "grass:3". A missing level counts as 0.
+func ParseEdgeLevel(s string) (string, int, error) {
+	kind, level, found := strings.Cut(s, ":")
+	if !found {
+		return kind, 0, nil
+	}
+	n, err := strconv.Atoi(level)
+	if err != nil {
+		return "", 0, fmt.Errorf("edge %q: invalid level: %w", s, err)
+	}
+	return kind, n, nil
+}
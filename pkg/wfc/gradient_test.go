@@ -0,0 +1,37 @@
+package wfc
+
+import "testing"
+
+func TestConnectEdgeLevelsIsPerWave(t *testing.T) {
+	levels := []int{0, 1, 3}
+	modules := make([]*Module, len(levels))
+	for i := range modules {
+		modules[i] = &Module{Index: i}
+	}
+	parse := func(m *Module, d Direction) (string, int) { return "grass", levels[m.Index] }
+
+	graded := NewFromModules(modules, 4, 4)
+	graded.ConnectEdgeLevels(parse)
+	plain := NewFromModules(modules, 4, 4)
+
+	tests := []struct {
+		a, b       int
+		graded, ok bool
+	}{
+		{0, 1, true, true},
+		{1, 2, false, true},
+		{0, 2, false, true},
+		{2, 2, true, true},
+	}
+	for _, tt := range tests {
+		a, b := modules[tt.a], modules[tt.b]
+		for _, d := range Directions {
+			if got := graded.CanNeighbor(a, b, d); got != tt.graded {
+				t.Errorf("graded wave: %d next to %d (%s) = %v, want %v", tt.a, tt.b, d.ToString(), got, tt.graded)
+			}
+			if got := plain.CanNeighbor(a, b, d); got != tt.ok {
+				t.Errorf("other wave: %d next to %d (%s) = %v, want %v", tt.a, tt.b, d.ToString(), got, tt.ok)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package wfc
+
+import (
+	"image"
+)
+
+// FinishGreedy deterministically fills all remaining uncollapsed slots. The
+// slots are visited in scan order (row by row) and each one is collapsed into
+// its highest-weight possible module, ties going to the earlier module in the
+// superposition. Every choice is propagated before moving on.
+//
+// Use this after a partial collapse to avoid contradictions caused by random
+// picks. It is not globally optimal, so it can still get stuck, in which case
+// ErrNoSolution is returned.
+func (w *Wave) FinishGreedy() error {
+	if err := w.checkInitialized(); err != nil {
+		return err
+	}
+
+	for y := 0; y < w.Height; y++ {
+		for x := 0; x < w.Width; x++ {
+			slot := w.GetSlot(x, y)
+			if len(slot.Superposition) == 0 {
+				return ErrNoSolution
+			}
+			if len(slot.Superposition) == 1 {
+				continue
+			}
+
+			best := slot.Superposition[0]
+			for _, m := range slot.Superposition[1:] {
+				if m.weight() > best.weight() {
+					best = m
+				}
+			}
+
+			w.steps++
+			slot.Superposition = []*Module{best}
+			slot.CollapsedAt = w.steps
+			w.decisions = append(w.decisions, image.Pt(x, y))
+			w.recordPlaced(slot)
+			if err := w.propagate(slot); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
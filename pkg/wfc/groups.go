@@ -0,0 +1,33 @@
+package wfc
+
+import "image"
+
+// TileGroup is a named set of input tiles, see NewFromGroups.
+type TileGroup struct {
+	Name  string
+	Tiles []image.Image
+}
+
+// NewFromGroups creates a new wave collapse function from several tilesets
+// whose adjacency rules are kept apart. Constraints are generated like New
+// does, but modules only neighbor modules of their own group. Connections
+// between groups have to be added explicitly with Allow.
+//
+// Modules are indexed in the order of the groups and their tiles, and their
+// Group field is set to the name of their group.
+func NewFromGroups(groups []TileGroup, width, height int) *Wave {
+	modules := make([]*Module, 0)
+	for _, g := range groups {
+		for _, tile := range g.Tiles {
+			module := Module{Index: len(modules), Image: tile, Group: g.Name}
+			for _, d := range Directions {
+				module.Adjacencies[d] = DefaultConstraintFunc(tile, d)
+			}
+			modules = append(modules, &module)
+		}
+	}
+
+	wave := NewFromModules(modules, width, height)
+	wave.ConstraintFn = DefaultConstraintFunc
+	return wave
+}
@@ -0,0 +1,77 @@
+package wfc
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sort"
+)
+
+var (
+	ErrUnknownModule = errors.New("module is not part of the wave input")
+)
+
+// InitializeWithHints sets up the wave like Initialize, then narrows the
+// superposition of the hinted slots down to the given candidate modules, e.g.
+// a cell that is "probably water" without being fixed to one exact tile. The
+// hints are propagated to their neighbors before returning, so the first call
+// to Collapse already works on the constrained state.
+//
+// Candidates that may not be placed in their slot (see BorderOnly,
+// InteriorOnly and AddMultiTile) are dropped. Every hint must lie inside the
+// grid, only contain modules from Input and keep at least one candidate,
+// otherwise an error is returned before the wave is touched.
+func (w *Wave) InitializeWithHints(seed int, hints map[image.Point][]*Module) error {
+	input := make(map[*Module]bool, len(w.Input))
+	for _, m := range w.Input {
+		input[m] = true
+	}
+
+	points := make([]image.Point, 0, len(hints))
+	allowed := make(map[image.Point][]*Module, len(hints))
+	for p, candidates := range hints {
+		if p.X < 0 || p.Y < 0 || p.X >= w.Width || p.Y >= w.Height {
+			return fmt.Errorf("hint at %d,%d is outside of the %dx%d grid",
+				p.X, p.Y, w.Width, w.Height)
+		}
+		modules := make([]*Module, 0, len(candidates))
+		for _, m := range candidates {
+			if !input[m] {
+				return fmt.Errorf("hint at %d,%d: %w", p.X, p.Y, ErrUnknownModule)
+			}
+			if w.placementAllows(p.X, p.Y, m) {
+				modules = append(modules, m)
+			}
+		}
+		if len(modules) == 0 {
+			return fmt.Errorf("slot %d,%d: %w", p.X, p.Y, ErrNoSolution)
+		}
+		allowed[p] = modules
+		points = append(points, p)
+	}
+
+	// Apply the hints in scan order so the result doesn't depend on map order
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Y != points[j].Y {
+			return points[i].Y < points[j].Y
+		}
+		return points[i].X < points[j].X
+	})
+
+	w.Initialize(seed)
+
+	for _, p := range points {
+		slot := w.GetSlot(p.X, p.Y)
+		slot.Superposition = allowed[p]
+		w.markCollapsed(slot)
+	}
+	w.recordInitial()
+
+	for _, p := range points {
+		if err := w.propagate(w.GetSlot(p.X, p.Y)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -106,8 +106,8 @@ func isImageFile(filename string) bool {
 
 // check if an image is completely transparent
 func tileIsTransparent(tile image.Image) bool {
-	for x := tile.Bounds().Min.X; x < tile.Bounds().Dx(); x++ {
-		for y := tile.Bounds().Min.Y; y < tile.Bounds().Dy(); y++ {
+	for x := tile.Bounds().Min.X; x < tile.Bounds().Max.X; x++ {
+		for y := tile.Bounds().Min.Y; y < tile.Bounds().Max.Y; y++ {
 			_, _, _, alpha := tile.At(x, y).RGBA()
 			if alpha != 0 {
 				return false
@@ -116,3 +116,21 @@ func tileIsTransparent(tile image.Image) bool {
 	}
 	return true
 }
+
+// imagesEqual checks if two images have the same size and pixel values.
+func imagesEqual(a, b image.Image) bool {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Dx() != bb.Dx() || ab.Dy() != bb.Dy() {
+		return false
+	}
+	for x := 0; x < ab.Dx(); x++ {
+		for y := 0; y < ab.Dy(); y++ {
+			r1, g1, b1, a1 := a.At(ab.Min.X+x, ab.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := b.At(bb.Min.X+x, bb.Min.Y+y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return false
+			}
+		}
+	}
+	return true
+}
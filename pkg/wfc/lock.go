@@ -0,0 +1,62 @@
+package wfc
+
+import "image"
+
+// LockCollapsed marks the collapsed slots inside the given rectangle (in slot
+// coordinates) as locked to their current module. Slots that aren't collapsed
+// are skipped. Locks persist until Unlock is called and are honored by
+// Regenerate, e.g. to keep the part of a map the user liked in an editor.
+//
+// Returns ErrNotInitialized if the wave has no possibility space to lock, i.e.
+// before Initialize or after Release.
+func (w *Wave) LockCollapsed(rect image.Rectangle) error {
+	if err := w.checkInitialized(); err != nil {
+		return err
+	}
+
+	rect = rect.Intersect(image.Rect(0, 0, w.Width, w.Height))
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			s := w.GetSlot(x, y)
+			if len(s.Superposition) != 1 {
+				continue
+			}
+			if w.locked == nil {
+				w.locked = make(map[image.Point]*Module)
+			}
+			w.locked[image.Pt(x, y)] = s.Superposition[0]
+		}
+	}
+
+	return nil
+}
+
+// Unlock removes the locks inside the given rectangle, see LockCollapsed.
+func (w *Wave) Unlock(rect image.Rectangle) {
+	for p := range w.locked {
+		if p.In(rect) {
+			delete(w.locked, p)
+		}
+	}
+}
+
+// Regenerate initializes the wave again with the given seed, keeping the
+// locked slots (see LockCollapsed) as hard constraints, and collapses the rest
+// with CollapseAuto. The locked slots are propagated to their unlocked
+// neighbors first, so the new content fits around them.
+//
+// Returns ErrNoSolution if the unlocked slots can't be filled in around the
+// locked ones.
+func (w *Wave) Regenerate(seed int) error {
+	hints := make(map[image.Point][]*Module, len(w.locked))
+	for p, m := range w.locked {
+		if p.X < w.Width && p.Y < w.Height {
+			hints[p] = []*Module{m}
+		}
+	}
+
+	if err := w.InitializeWithHints(seed, hints); err != nil {
+		return err
+	}
+	return w.CollapseAuto()
+}
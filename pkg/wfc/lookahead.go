@@ -0,0 +1,110 @@
+package wfc
+
+import (
+	"math"
+)
+
+// ScoreFunc rates a partially collapsed wave for CollapseLookahead, higher is
+// better.
+type ScoreFunc func(w *Wave) float64
+
+// CollapseLookahead collapses the wave one slot at a time like Step, but
+// instead of picking the module of a slot at random, it tries every module
+// left in the slot, propagates the choice and keeps the one whose outcome
+// scores best. With a nil score function the outcome is scored by
+// TotalPossibilities, which picks the least constraining module and avoids
+// many contradictions. Modules whose propagation fails are never picked,
+// unless nothing else is left. Ties are broken at random.
+//
+// With a depth above 1, each outcome is scored by the best outcome of the next
+// depth-1 decisions instead. This is expensive: every decision simulates up to
+// m^depth collapses for m modules, so keep the depth small.
+//
+// Returns the propagation error if a contradiction is found anyway.
+func (w *Wave) CollapseLookahead(depth int, score ScoreFunc) error {
+	if err := w.checkInitialized(); err != nil {
+		return err
+	}
+	if depth < 1 {
+		depth = 1
+	}
+	if score == nil {
+		score = func(w *Wave) float64 {
+			return float64(w.TotalPossibilities())
+		}
+	}
+
+	for !w.IsCollapsed() {
+		slot := w.nextSlot()
+		if slot == nil {
+			return nil
+		}
+
+		if m := w.bestModule(slot, depth, score); m != nil {
+			w.decideModule(slot, m)
+		} else {
+			w.decide(slot)
+		}
+		if err := w.propagate(slot); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bestModule returns the module of a slot with the best lookahead score, or
+// nil if every module leads to a contradiction.
+func (w *Wave) bestModule(s *Slot, depth int, score ScoreFunc) *Module {
+	best := make([]*Module, 0)
+	bestScore := math.Inf(-1)
+	for _, m := range s.Superposition {
+		v, ok := w.lookahead(s, m, depth, score)
+		if !ok {
+			continue
+		}
+		if v > bestScore {
+			best, bestScore = best[:0], v
+		}
+		if v == bestScore {
+			best = append(best, m)
+		}
+	}
+	if len(best) == 0 {
+		return nil
+	}
+	return best[w.rng.Intn(len(best))]
+}
+
+// lookahead collapses a slot into a module, propagates it and scores the
+// outcome, looking depth-1 decisions further ahead. The wave is restored
+// afterwards. Returns false if the outcome is a contradiction.
+func (w *Wave) lookahead(s *Slot, m *Module, depth int, score ScoreFunc) (float64, bool) {
+	state := w.snapshot()
+	steps, log := w.steps, len(w.decisions)
+	defer func() {
+		w.restore(state)
+		w.steps = steps
+		w.decisions = w.decisions[:log]
+	}()
+
+	w.decideModule(s, m)
+	if err := w.propagate(s); err != nil {
+		return 0, false
+	}
+	if depth <= 1 || w.IsCollapsed() {
+		return score(w), true
+	}
+
+	next := w.nextSlot()
+	if next == nil {
+		return score(w), true
+	}
+	best, found := math.Inf(-1), false
+	for _, o := range next.Superposition {
+		if v, ok := w.lookahead(next, o, depth-1, score); ok && v > best {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,50 @@
+package wfc
+
+import (
+	"image"
+	"image/color"
+)
+
+// GenerateMarchingSquaresTiles generates the 16 marching squares tiles, one for
+// every combination of filled and empty corners, so a tileset can be built
+// without drawing anything. Tile i has its top left, top right, bottom right
+// and bottom left corner filled if bit 0, 1, 2 and 3 of i are set.
+//
+// The corners are blended across the tile, and the blended value is
+// quantized into four bands drawn with the given colors, from colors[0] for
+// empty areas to colors[3] for filled ones, e.g. water, shallows, sand and
+// grass. The pixels along an edge only depend on the two corners of that edge,
+// so the tiles can be fed straight into New and connect wherever their shared
+// corners agree.
+func GenerateMarchingSquaresTiles(colors [4]color.Color, tileSize int) []image.Image {
+	tiles := make([]image.Image, 16)
+	for i := range tiles {
+		corner := func(bit int) float64 {
+			if i&(1<<bit) != 0 {
+				return 1
+			}
+			return 0
+		}
+		tl, tr, br, bl := corner(0), corner(1), corner(2), corner(3)
+
+		img := image.NewRGBA(image.Rect(0, 0, tileSize, tileSize))
+		for x := 0; x < tileSize; x++ {
+			for y := 0; y < tileSize; y++ {
+				u, v := 0.0, 0.0
+				if tileSize > 1 {
+					u = float64(x) / float64(tileSize-1)
+					v = float64(y) / float64(tileSize-1)
+				}
+				f := tl*(1-u)*(1-v) + tr*u*(1-v) + br*u*v + bl*(1-u)*v
+
+				band := int(f * 4)
+				if band > 3 {
+					band = 3
+				}
+				img.Set(x, y, colors[band])
+			}
+		}
+		tiles[i] = img
+	}
+	return tiles
+}
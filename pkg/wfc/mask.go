@@ -0,0 +1,128 @@
+package wfc
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// InitializeFromMask sets up the wave like InitializeWithHints, restricting
+// every slot to the modules tagged with the biome of the matching pixel of a
+// mask. The mask has one pixel per slot, e.g. a hand-drawn sketch where each
+// color stands for a biome, and biomeTags maps those colors to module tags
+// (see Module.Tags). Colors are compared by their RGBA values, regardless of
+// the color model of the mask. Pixels with a color that isn't in biomeTags
+// leave their slot unrestricted.
+//
+// Returns an error if the mask doesn't match the size of the grid, and
+// ErrNoSolution if a biome leaves a slot without modules or the restrictions
+// contradict each other.
+func (w *Wave) InitializeFromMask(
+	mask image.Image, biomeTags map[color.Color]string, seed int,
+) error {
+	b := mask.Bounds()
+	if b.Dx() != w.Width || b.Dy() != w.Height {
+		return fmt.Errorf("mask is %dx%d, expected %dx%d",
+			b.Dx(), b.Dy(), w.Width, w.Height)
+	}
+
+	tags := make(map[color.RGBA64]string, len(biomeTags))
+	for c, tag := range biomeTags {
+		tags[rgba64(c)] = tag
+	}
+
+	hints := make(map[image.Point][]*Module)
+	for x := 0; x < w.Width; x++ {
+		for y := 0; y < w.Height; y++ {
+			tag, ok := tags[rgba64(mask.At(b.Min.X+x, b.Min.Y+y))]
+			if !ok {
+				continue
+			}
+
+			allowed := make([]*Module, 0)
+			for _, m := range w.allowedAt(x, y) {
+				if m.HasTag(tag) {
+					allowed = append(allowed, m)
+				}
+			}
+			if len(allowed) == 0 {
+				return fmt.Errorf("slot %d,%d has no module tagged %q: %w",
+					x, y, tag, ErrNoSolution)
+			}
+			hints[image.Pt(x, y)] = allowed
+		}
+	}
+
+	return w.InitializeWithHints(seed, hints)
+}
+
+// rgba64 normalizes a color to its RGBA values, so colors from different color
+// models can be compared.
+func rgba64(c color.Color) color.RGBA64 {
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{uint16(r), uint16(g), uint16(b), uint16(a)}
+}
+
+// NoiseBand is a range of noise values and the modules allowed in slots whose
+// noise value falls into it, see InitializeFromNoiseField.
+type NoiseBand struct {
+	Min, Max float64   // Range of noise values, Min inclusive and Max exclusive
+	Modules  []*Module // Modules allowed in the band
+}
+
+// InitializeFromNoiseField sets up the wave like InitializeWithHints,
+// restricting every slot to the modules of the bands its noise value falls
+// into, e.g. to get large scale biomes from Perlin or simplex noise: water for
+// low values, mountains for high ones. The wave then only has to make the
+// biomes meet coherently. The noise function is called once per slot. A slot
+// in several bands allows the modules of all of them, a slot in no band is
+// unrestricted.
+//
+// Returns ErrUnknownModule if a band contains a module that isn't part of the
+// input, and ErrNoSolution if the bands leave a slot without modules or the
+// restrictions contradict each other.
+func (w *Wave) InitializeFromNoiseField(
+	noise func(x, y int) float64, bands []NoiseBand, seed int,
+) error {
+	for i, band := range bands {
+		for _, m := range band.Modules {
+			if !containsModule(w.Input, m) {
+				return fmt.Errorf("band %d: %w", i, ErrUnknownModule)
+			}
+		}
+	}
+
+	hints := make(map[image.Point][]*Module)
+	for x := 0; x < w.Width; x++ {
+		for y := 0; y < w.Height; y++ {
+			v := noise(x, y)
+			in := false
+			modules := make(map[*Module]bool)
+			for _, band := range bands {
+				if v >= band.Min && v < band.Max {
+					in = true
+					for _, m := range band.Modules {
+						modules[m] = true
+					}
+				}
+			}
+			if !in {
+				continue
+			}
+
+			allowed := make([]*Module, 0)
+			for _, m := range w.allowedAt(x, y) {
+				if modules[m] {
+					allowed = append(allowed, m)
+				}
+			}
+			if len(allowed) == 0 {
+				return fmt.Errorf("slot %d,%d with noise %g has no module: %w",
+					x, y, v, ErrNoSolution)
+			}
+			hints[image.Pt(x, y)] = allowed
+		}
+	}
+
+	return w.InitializeWithHints(seed, hints)
+}
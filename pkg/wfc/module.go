@@ -2,8 +2,13 @@ package wfc
 
 import (
 	"image"
+	"sync/atomic"
 )
 
+// Version of the rules recorded on modules with Forbid and Allow. Waves compare
+// it to the version their memoized adjacency was computed with.
+var moduleRules uint64
+
 // Module represents a single module in the wave function as described by Oskar
 // Stalberg. A module is a possible tile that might exist at a slot in the wave
 // function grid. It can be thought of as a single state of a superposition.
@@ -11,18 +16,107 @@ type Module struct {
 	Index       int             // The index of the module in the input tiles
 	Adjacencies [4]ConstraintId // Adjacency constraints for each direction
 	Image       image.Image     // The tile image for the module
+	Weight      float64         // Relative likelihood of the module, values <= 0 count as 1
+
+	// Orientation relative to the source tile. The source is rotated
+	// clockwise by Rotation quarter turns first and then mirrored
+	// horizontally if Reflected is set, regardless of the order in which
+	// RotateModule and ReflectModule were applied.
+	Rotation  int     // Clockwise quarter turns applied to the source tile
+	Reflected bool    // Whether the rotated source tile was mirrored horizontally
+	Source    *Module // The module this one was derived from, nil for original tiles
+
+	// Tile group of the module, see NewFromGroups. Modules only neighbor
+	// modules of the same group unless the pairing is explicitly allowed.
+	Group string
+
+	Tags []string // Free-form labels, e.g. from a tileset.json, see LoadTileset
+
+	forbidden [4]map[*Module]bool // Neighbors disallowed in each direction, see Forbid
+	allowed   [4]map[*Module]bool // Neighbors explicitly allowed in each direction, see Allow
 }
 
 // IsPossibleFrom returns true if the given module is possible from the given
 // direction.
 func (m *Module) IsPossibleFrom(from *Slot, forward Direction) bool {
-	backward := forward.Opposite()
-
 	for _, c := range from.Superposition {
-		if m.Adjacencies[backward].Equal(c.Adjacencies[forward]) {
+		if c.CanNeighbor(m, forward) {
 			return true
 		}
 	}
 
 	return false
 }
+
+// CanNeighbor returns true if module "o" may be placed next to "this" module
+// in the given direction. The pairing must not have been disallowed with
+// Forbid. If it was allowed with Allow it is possible, otherwise both modules
+// must be in the same group and their adjacency constraints must match.
+func (m *Module) CanNeighbor(o *Module, d Direction) bool {
+	if m.forbidden[d][o] {
+		return false
+	}
+	if m.allowed[d][o] {
+		return true
+	}
+	if m.Group != o.Group {
+		return false
+	}
+	return m.Adjacencies[d].Equal(o.Adjacencies[d.Opposite()])
+}
+
+// Forbid disallows module "b" from being placed next to module "a" in the
+// given direction, even if their adjacency constraints match. The rule is
+// recorded on both modules, so it holds in either direction of propagation.
+//
+// Use this to break connections that match pixel-wise but look wrong. The
+// rule is honored by DefaultIsPossibleFunc and everything else built on
+// Module.CanNeighbor.
+func Forbid(a, b *Module, d Direction) {
+	a.forbid(b, d)
+	b.forbid(a, d.Opposite())
+	atomic.AddUint64(&moduleRules, 1)
+}
+
+// Allow lets module "b" be placed next to module "a" in the given direction,
+// regardless of their groups and adjacency constraints. Like Forbid, the rule
+// is recorded on both modules. Forbid takes precedence over Allow.
+//
+// Use this to bridge tile groups created with NewFromGroups.
+func Allow(a, b *Module, d Direction) {
+	a.allow(b, d)
+	b.allow(a, d.Opposite())
+	atomic.AddUint64(&moduleRules, 1)
+}
+
+func (m *Module) allow(o *Module, d Direction) {
+	if m.allowed[d] == nil {
+		m.allowed[d] = make(map[*Module]bool)
+	}
+	m.allowed[d][o] = true
+}
+
+func (m *Module) forbid(o *Module, d Direction) {
+	if m.forbidden[d] == nil {
+		m.forbidden[d] = make(map[*Module]bool)
+	}
+	m.forbidden[d][o] = true
+}
+
+// HasTag returns true if the module has the given tag, see Module.Tags.
+func (m *Module) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// weight returns the effective weight of the module.
+func (m *Module) weight() float64 {
+	if m.Weight <= 0 {
+		return 1
+	}
+	return m.Weight
+}
@@ -0,0 +1,190 @@
+package wfc
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+var (
+	ErrInvalidMultiTile = errors.New("invalid multi-tile module")
+)
+
+// MultiTileModule is a structure spanning several slots, e.g. a 2x2 castle,
+// whose modules must always be placed together. See AddMultiTile.
+type MultiTileModule struct {
+	// Layout of the structure, indexed [y][x]. Nil cells are not part of
+	// the structure and may hold anything.
+	Tiles [][]*Module
+
+	// Cell of the layout that marks the position of the structure, e.g. the
+	// entrance of a building. See MultiTilePlacements.
+	Anchor image.Point
+}
+
+// multiTilePart is the position of a module within a multi-tile structure.
+type multiTilePart struct {
+	mt   *MultiTileModule
+	x, y int
+}
+
+// AddMultiTile makes the modules of a multi-tile structure appear together. As
+// soon as a slot collapses into one of them, the slots around it are collapsed
+// into the rest of the structure and the changes are propagated. If one of
+// them doesn't allow its module, the slot ends up in a contradiction, which
+// CollapseAuto resolves by backtracking. Neighboring modules of the layout are
+// allowed next to each other for this wave only (see CanNeighbor), unless the
+// pairing is ruled out with Forbid or by the rules of the wave. A module is
+// left out of every slot where its structure would only partially fit into
+// the grid.
+//
+// Every module must be part of the input and may only be used once across all
+// structures, otherwise ErrUnknownModule or ErrInvalidMultiTile is returned.
+// Call this before Initialize.
+func (w *Wave) AddMultiTile(mt *MultiTileModule) error {
+	if mt.Anchor.Y < 0 || mt.Anchor.Y >= len(mt.Tiles) ||
+		mt.Anchor.X < 0 || mt.Anchor.X >= len(mt.Tiles[mt.Anchor.Y]) ||
+		mt.Tiles[mt.Anchor.Y][mt.Anchor.X] == nil {
+		return fmt.Errorf("anchor %d,%d is not part of the layout: %w",
+			mt.Anchor.X, mt.Anchor.Y, ErrInvalidMultiTile)
+	}
+
+	seen := make(map[*Module]bool)
+	for y, row := range mt.Tiles {
+		for x, m := range row {
+			if m == nil {
+				continue
+			}
+			if !containsModule(w.Input, m) {
+				return fmt.Errorf("cell %d,%d: %w", x, y, ErrUnknownModule)
+			}
+			if _, ok := w.multiTileParts[m]; ok || seen[m] {
+				return fmt.Errorf("cell %d,%d: module %d is used more than once: %w",
+					x, y, m.Index, ErrInvalidMultiTile)
+			}
+			seen[m] = true
+		}
+	}
+
+	if w.multiTileParts == nil {
+		w.multiTileParts = make(map[*Module]multiTilePart)
+	}
+	for y, row := range mt.Tiles {
+		for x, m := range row {
+			if m == nil {
+				continue
+			}
+			w.multiTileParts[m] = multiTilePart{mt, x, y}
+			if right := mt.cell(x+1, y); right != nil {
+				w.allowPair(m, right, Right)
+			}
+			if down := mt.cell(x, y+1); down != nil {
+				w.allowPair(m, down, Down)
+			}
+		}
+	}
+
+	return nil
+}
+
+// MultiTilePlacements returns the slots the anchor of every multi-tile
+// structure has been placed at, for the structures that are complete.
+func (w *Wave) MultiTilePlacements() map[*MultiTileModule][]image.Point {
+	res := make(map[*MultiTileModule][]image.Point)
+	for _, s := range w.PossibilitySpace {
+		if len(s.Superposition) != 1 {
+			continue
+		}
+		part, ok := w.multiTileParts[s.Superposition[0]]
+		if !ok || part.x != part.mt.Anchor.X || part.y != part.mt.Anchor.Y {
+			continue
+		}
+		if w.multiTileComplete(part.mt, s.X-part.x, s.Y-part.y) {
+			res[part.mt] = append(res[part.mt], image.Pt(s.X, s.Y))
+		}
+	}
+	return res
+}
+
+// cell returns the module of the layout at the given position, or nil if the
+// position is outside of the layout.
+func (mt *MultiTileModule) cell(x, y int) *Module {
+	if y < 0 || y >= len(mt.Tiles) || x < 0 || x >= len(mt.Tiles[y]) {
+		return nil
+	}
+	return mt.Tiles[y][x]
+}
+
+// multiTileFits checks if the structure of a module fits into the grid when
+// the module is placed at the given coordinates. Modules that aren't part of a
+// structure always fit.
+func (w *Wave) multiTileFits(x, y int, m *Module) bool {
+	part, ok := w.multiTileParts[m]
+	if !ok {
+		return true
+	}
+	for py, row := range part.mt.Tiles {
+		for px, c := range row {
+			if c == nil {
+				continue
+			}
+			cx, cy := x-part.x+px, y-part.y+py
+			if (!w.WrapX && (cx < 0 || cx >= w.Width)) || (!w.WrapY && (cy < 0 || cy >= w.Height)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// multiTileComplete checks if every cell of a structure placed with its top
+// left corner at the given coordinates is collapsed into its module.
+func (w *Wave) multiTileComplete(mt *MultiTileModule, x0, y0 int) bool {
+	for py, row := range mt.Tiles {
+		for px, c := range row {
+			if c == nil {
+				continue
+			}
+			s := w.patternSlot(x0+px, y0+py)
+			if s == nil || len(s.Superposition) != 1 || s.Superposition[0] != c {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// enforceMultiTile collapses the slots around a slot that was collapsed into a
+// module of a multi-tile structure into the rest of the structure, and
+// propagates the changes. Returns ErrNoSolution if the structure doesn't fit.
+func (w *Wave) enforceMultiTile(s *Slot) error {
+	part, ok := w.multiTileParts[s.Superposition[0]]
+	if !ok {
+		return nil
+	}
+
+	x0, y0 := s.X-part.x, s.Y-part.y
+	for py, row := range part.mt.Tiles {
+		for px, c := range row {
+			if c == nil {
+				continue
+			}
+			o := w.patternSlot(x0+px, y0+py)
+			if o == nil || !containsModule(o.Superposition, c) {
+				if w.Logger != nil {
+					w.Logger.Debug("contradiction", "x", s.X, "y", s.Y, "multitile", true)
+				}
+				return ErrNoSolution
+			}
+			if len(o.Superposition) == 1 {
+				continue
+			}
+			o.Superposition = []*Module{c}
+			w.markCollapsed(o)
+			if err := w.propagate(o); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
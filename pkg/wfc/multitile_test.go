@@ -0,0 +1,120 @@
+package wfc
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"testing"
+)
+
+// newCastle returns a wave with the four modules of a 2x2 structure and a
+// filler module. The modules of the structure are in groups of their own, so
+// they may only be placed next to the filler, except where AddMultiTile pairs
+// them.
+func newCastle(width, height int) (*Wave, *MultiTileModule) {
+	filler := &Module{Index: 4}
+	in := []*Module{filler}
+	for i := 0; i < 4; i++ {
+		m := &Module{Index: i, Group: fmt.Sprintf("castle %d", i)}
+		for _, d := range Directions {
+			Allow(filler, m, d)
+		}
+		in = append(in, m)
+	}
+	in = append(in[1:], filler)
+
+	w := NewFromModules(in, width, height)
+	mt := &MultiTileModule{Tiles: [][]*Module{{in[0], in[1]}, {in[2], in[3]}}, Anchor: image.Pt(1, 1)}
+	return w, mt
+}
+
+func TestMultiTilePlacedAtomically(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		forbid bool         // Forbid the top row of the structure
+		hint   *image.Point // Slot hinted to module 0, the top left cell
+		want   []image.Point
+		placed bool // Some seed places the structure
+		err    error
+	}{
+		{"free", 6, 6, false, nil, nil, true, nil},
+		{"hinted", 4, 4, false, &image.Point{1, 2}, []image.Point{{2, 3}}, true, nil},
+		{"too small", 1, 2, false, nil, nil, false, nil},
+		{"hint doesn't fit", 4, 4, false, &image.Point{3, 0}, nil, false, ErrNoSolution},
+		{"forbidden", 6, 6, true, nil, nil, false, nil},
+		{"forbidden and hinted", 4, 4, true, &image.Point{1, 2}, nil, false, ErrNoSolution},
+	}
+
+	for _, tt := range tests {
+		placed := false
+		for seed := 0; seed < 5; seed++ {
+			w, mt := newCastle(tt.width, tt.height)
+			if tt.forbid {
+				Forbid(w.Input[0], w.Input[1], Right)
+			}
+			if err := w.AddMultiTile(mt); err != nil {
+				t.Fatalf("%s: AddMultiTile: %v", tt.name, err)
+			}
+
+			var err error
+			if tt.hint != nil {
+				err = w.InitializeWithHints(seed, map[image.Point][]*Module{*tt.hint: {w.Input[0]}})
+			} else {
+				w.Initialize(seed)
+			}
+			if err == nil {
+				err = w.CollapseAuto()
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s, seed %d: got %v, want %v", tt.name, seed, err, tt.err)
+				continue
+			}
+			if tt.err != nil {
+				continue
+			}
+
+			// Every placed part belongs to a complete structure
+			for _, s := range w.PossibilitySpace {
+				part, ok := w.multiTileParts[s.Superposition[0]]
+				if ok && !w.multiTileComplete(mt, s.X-part.x, s.Y-part.y) {
+					t.Errorf("%s, seed %d: partial structure at %d,%d", tt.name, seed, s.X, s.Y)
+				}
+			}
+
+			got := w.MultiTilePlacements()[mt]
+			placed = placed || len(got) > 0
+			if tt.want != nil && (len(got) != len(tt.want) || got[0] != tt.want[0]) {
+				t.Errorf("%s, seed %d: placements = %v, want %v", tt.name, seed, got, tt.want)
+			}
+		}
+		if placed != tt.placed {
+			t.Errorf("%s: structure placed = %v, want %v", tt.name, placed, tt.placed)
+		}
+	}
+}
+
+func TestAddMultiTileRejectsInvalidLayouts(t *testing.T) {
+	w, _ := newCastle(4, 4)
+	in := w.Input
+
+	tests := []struct {
+		name string
+		mt   *MultiTileModule
+		err  error
+	}{
+		{"anchor outside", &MultiTileModule{Tiles: [][]*Module{{in[0]}}, Anchor: image.Pt(1, 0)}, ErrInvalidMultiTile},
+		{"anchor on wildcard", &MultiTileModule{Tiles: [][]*Module{{in[0], nil}}, Anchor: image.Pt(1, 0)}, ErrInvalidMultiTile},
+		{"duplicate module", &MultiTileModule{Tiles: [][]*Module{{in[0], in[0]}}}, ErrInvalidMultiTile},
+		{"unknown module", &MultiTileModule{Tiles: [][]*Module{{{Index: 9}}}}, ErrUnknownModule},
+	}
+	for _, tt := range tests {
+		if err := w.AddMultiTile(tt.mt); !errors.Is(err, tt.err) {
+			t.Errorf("%s: AddMultiTile() = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if len(w.multiTileParts) != 0 {
+		t.Error("rejected layouts were registered")
+	}
+}
@@ -0,0 +1,137 @@
+package wfc
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Below this number of slots to update in a round, the round is computed on
+// the calling goroutine.
+const minParallelSlots = 64
+
+// stepParallel collapses the next slot and propagates the change with
+// propagateParallel.
+func (w *Wave) stepParallel() error {
+	slot := w.collapseNextSlot()
+	if slot == nil {
+		return nil
+	}
+	w.logCollapse(slot)
+	return w.propagateParallel(slot)
+}
+
+// propagateParallel removes impossible modules from the wave after the given
+// slot changed, using several goroutines. It is used instead of Recurse when
+// ParallelPropagation is enabled.
+//
+// Propagation runs in rounds. Each round recomputes the superposition of every
+// neighbor of the slots that changed in the previous round, against all four
+// of its neighbors. The grid is partitioned into horizontal bands, one per
+// worker, and the workers only read the state of the previous round, so no
+// locking is needed. The new superpositions are applied between rounds, which
+// is where the bands synchronize. Rounds repeat until nothing changes.
+//
+// Every slot is checked against all of its neighbors, collapsed ones
+// included, so a removal that Recurse would turn into a contradiction is
+// found here too. The order of removals differs from Recurse, so the two may
+// report a contradiction at different slots.
+func (w *Wave) propagateParallel(start *Slot) error {
+	workers := runtime.NumCPU()
+	changed := []*Slot{start}
+
+	for round := 1; len(changed) > 0; round++ {
+		w.recordDepth(round)
+
+		// Collect the slots to recompute, visiting each one once
+		seen := make(map[*Slot]bool)
+		pending := make([]*Slot, 0)
+		for _, s := range changed {
+			for _, d := range Directions {
+				if !w.HasNeighbor(s, d) {
+					continue
+				}
+				n := w.GetNeighbor(s, d)
+				// Collapsed neighbors are checked too, their module may
+				// have lost its support
+				if seen[n] || len(n.Superposition) == 0 {
+					continue
+				}
+				seen[n] = true
+				pending = append(pending, n)
+			}
+		}
+
+		// Compute the new superpositions, partitioned into bands of rows
+		results := make([][]*Module, len(pending))
+		if len(pending) < minParallelSlots || workers == 1 {
+			for i, s := range pending {
+				results[i] = w.constrainedSuperposition(s)
+			}
+		} else {
+			bands := make([][]int, workers)
+			for i, s := range pending {
+				band := s.Y * workers / w.Height
+				bands[band] = append(bands[band], i)
+			}
+
+			var wg sync.WaitGroup
+			for _, band := range bands {
+				if len(band) == 0 {
+					continue
+				}
+				wg.Add(1)
+				go func(band []int) {
+					defer wg.Done()
+					for _, i := range band {
+						results[i] = w.constrainedSuperposition(pending[i])
+					}
+				}(band)
+			}
+			wg.Wait()
+		}
+
+		// Synchronize: apply the results and find the slots that changed
+		changed = changed[:0]
+		for i, s := range pending {
+			if len(results[i]) == len(s.Superposition) {
+				continue
+			}
+			w.logPropagate(s, len(s.Superposition)-len(results[i]), len(results[i]))
+			s.Superposition = results[i]
+			if len(s.Superposition) == 0 {
+				w.logContradiction(s)
+				return ErrNoSolution
+			}
+			w.updateEntropy(s)
+			w.markCollapsed(s)
+			changed = append(changed, s)
+		}
+	}
+
+	return nil
+}
+
+// constrainedSuperposition returns the modules of a slot that are possible
+// from all of its neighbors. It only reads the wave, so it may be called
+// concurrently.
+func (w *Wave) constrainedSuperposition(s *Slot) []*Module {
+	res := make([]*Module, 0, len(s.Superposition))
+	for _, m := range s.Superposition {
+		possible := true
+		for _, d := range Directions {
+			if !w.HasNeighbor(s, d) {
+				continue
+			}
+			n := w.GetNeighbor(s, d)
+			w.countChecks(1)
+			if !w.isPossible(m, n, s, d.Opposite()) {
+				possible = false
+				break
+			}
+		}
+		if possible {
+			res = append(res, m)
+		}
+	}
+	return res
+}
@@ -0,0 +1,134 @@
+package wfc
+
+// ForbidPattern keeps a small block of modules from appearing in the output,
+// e.g. three lava tiles in a row. The pattern is indexed [y][x]; nil cells are
+// wildcards that match anything. As soon as all but one cell of the pattern
+// are collapsed into the modules it names at some position, the remaining
+// module is removed from the last cell and the change is propagated. Patterns
+// only match where they fit inside the grid, or across its edges if it wraps
+// around.
+//
+// This is a higher-order rule that can't be expressed with pairwise adjacency.
+// If propagation leaves the last cell with the forbidden module as its only
+// option, the slot ends up in a contradiction, which CollapseAuto resolves by
+// backtracking. Patterns the tileset forces can't be ruled out this way.
+//
+// Call this before collapsing the wave.
+func (w *Wave) ForbidPattern(pattern [][]*Module) {
+	w.patterns = append(w.patterns, pattern)
+}
+
+// ForbidSquareBlocks keeps the given module from forming 2x2 blocks, a common
+// rule against blocky, uniform output. It is a shorthand for ForbidPattern.
+func (w *Wave) ForbidSquareBlocks(m *Module) {
+	w.ForbidPattern([][]*Module{{m, m}, {m, m}})
+}
+
+// queuePatterns records a slot that was just collapsed, to check it against the
+// forbidden patterns and multi-tile structures.
+func (w *Wave) queuePatterns(s *Slot) {
+	if len(w.patterns) > 0 || len(w.multiTileParts) > 0 {
+		w.patternQueue = append(w.patternQueue, s)
+	}
+}
+
+// enforcePatterns removes modules from the slots that would complete a
+// forbidden pattern with the queued slots, completes the multi-tile structures
+// they are part of, and propagates the changes. Returns ErrNoSolution if a
+// pattern was completed, a structure doesn't fit or a slot is left without
+// modules.
+func (w *Wave) enforcePatterns() error {
+	for len(w.patternQueue) > 0 {
+		s := w.patternQueue[0]
+		w.patternQueue = w.patternQueue[1:]
+		if len(s.Superposition) != 1 {
+			continue
+		}
+		m := s.Superposition[0]
+
+		if err := w.enforceMultiTile(s); err != nil {
+			return err
+		}
+
+		for _, pattern := range w.patterns {
+			for py, row := range pattern {
+				for px, c := range row {
+					if c != m {
+						continue
+					}
+					if err := w.enforcePatternAt(pattern, s.X-px, s.Y-py); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// enforcePatternAt checks a forbidden pattern placed with its top left corner
+// at the given coordinates. If all cells but one match, the required module is
+// removed from the open cell.
+func (w *Wave) enforcePatternAt(pattern [][]*Module, x0, y0 int) error {
+	var open *Slot
+	var module *Module
+	for py, row := range pattern {
+		for px, c := range row {
+			if c == nil {
+				continue
+			}
+			s := w.patternSlot(x0+px, y0+py)
+			if s == nil {
+				return nil
+			}
+			if len(s.Superposition) == 1 && s.Superposition[0] == c {
+				continue
+			}
+			if open != nil || !containsModule(s.Superposition, c) {
+				// Two open cells, or a cell that can't match anymore
+				return nil
+			}
+			open, module = s, c
+		}
+	}
+
+	if open == nil {
+		if w.Logger != nil {
+			w.Logger.Debug("contradiction", "x", x0, "y", y0, "pattern", true)
+		}
+		return ErrNoSolution
+	}
+
+	open.Superposition = removeModule(open.Superposition, module)
+	if len(open.Superposition) == 0 {
+		return ErrNoSolution
+	}
+	w.updateEntropy(open)
+	w.markCollapsed(open)
+	return w.propagate(open)
+}
+
+// patternSlot returns the slot at the given coordinates, wrapping them around
+// the grid if it wraps. Returns nil if they are outside of the grid.
+func (w *Wave) patternSlot(x, y int) *Slot {
+	if w.WrapX {
+		x = ((x % w.Width) + w.Width) % w.Width
+	}
+	if w.WrapY {
+		y = ((y % w.Height) + w.Height) % w.Height
+	}
+	if x < 0 || y < 0 || x >= w.Width || y >= w.Height {
+		return nil
+	}
+	return w.GetSlot(x, y)
+}
+
+// containsModule checks if a list of modules contains the given module.
+func containsModule(modules []*Module, m *Module) bool {
+	for _, o := range modules {
+		if o == m {
+			return true
+		}
+	}
+	return false
+}
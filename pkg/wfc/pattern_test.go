@@ -0,0 +1,105 @@
+package wfc
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestForbidPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		width   int
+		wrap    bool
+		pattern []int // Pattern row of module indices, -1 for wildcards
+		hints   map[image.Point]int
+		err     error
+		want    map[image.Point]int
+	}{
+		{
+			name:    "pair",
+			width:   3,
+			pattern: []int{0, 0},
+			hints:   map[image.Point]int{{0, 0}: 0},
+			want:    map[image.Point]int{{1, 0}: 1},
+		},
+		{
+			name:    "pair across the edge",
+			width:   3,
+			pattern: []int{0, 0},
+			hints:   map[image.Point]int{{0, 0}: 0, {2, 0}: 0},
+			want:    map[image.Point]int{{1, 0}: 1},
+		},
+		{
+			name:    "pair across the wrapped edge",
+			width:   3,
+			wrap:    true,
+			pattern: []int{0, 0},
+			hints:   map[image.Point]int{{0, 0}: 0, {2, 0}: 0},
+			err:     ErrNoSolution,
+		},
+		{
+			name:    "wildcard",
+			width:   3,
+			pattern: []int{0, -1, 0},
+			hints:   map[image.Point]int{{0, 0}: 0, {1, 0}: 0},
+			want:    map[image.Point]int{{2, 0}: 1},
+		},
+		{
+			name:    "wildcard across the wrapped edge",
+			width:   4,
+			wrap:    true,
+			pattern: []int{0, -1, 0},
+			hints:   map[image.Point]int{{3, 0}: 0, {0, 0}: 0},
+			want:    map[image.Point]int{{1, 0}: 1, {2, 0}: 1},
+		},
+		{
+			name:    "pattern wider than the grid",
+			width:   2,
+			pattern: []int{0, -1, 0},
+			hints:   map[image.Point]int{{0, 0}: 0, {1, 0}: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		w := NewSymbolic(2, func(a, b int, d Direction) bool { return true }, tt.width, 1)
+		w.WrapX = tt.wrap
+
+		pattern := make([]*Module, len(tt.pattern))
+		for i, idx := range tt.pattern {
+			if idx >= 0 {
+				pattern[i] = w.Input[idx]
+			}
+		}
+		w.ForbidPattern([][]*Module{pattern})
+
+		hints := make(map[image.Point][]*Module, len(tt.hints))
+		for p, idx := range tt.hints {
+			hints[p] = []*Module{w.Input[idx]}
+		}
+		// A completed pattern may already be found while the hints are
+		// propagated
+		err := w.InitializeWithHints(1, hints)
+		if err == nil {
+			err = w.CollapseAuto()
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if tt.err != nil {
+			continue
+		}
+
+		for p, idx := range tt.want {
+			if got := w.GetSlot(p.X, p.Y).Superposition[0].Index; got != idx {
+				t.Errorf("%s: slot %d,%d = module %d, want %d", tt.name, p.X, p.Y, got, idx)
+			}
+		}
+		for x := 0; x < w.Width; x++ {
+			if w.matchesPattern([][]*Module{pattern}, x, 0, nil, nil) {
+				t.Errorf("%s: pattern found at %d,0", tt.name, x)
+			}
+		}
+	}
+}
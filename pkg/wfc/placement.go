@@ -0,0 +1,59 @@
+package wfc
+
+// BorderOnly restricts the given module to the outer ring of the grid. It is
+// removed from the superposition of every interior slot when the wave is
+// initialized. Useful for walls or frames around the edge of a map.
+//
+// Call this before Initialize. Use Validate afterwards to make sure the
+// remaining modules still leave every slot with at least one option.
+func (w *Wave) BorderOnly(m *Module) {
+	if w.borderOnly == nil {
+		w.borderOnly = make(map[*Module]bool)
+	}
+	w.borderOnly[m] = true
+	delete(w.interiorOnly, m)
+}
+
+// InteriorOnly keeps the given module off the outer ring of the grid. It is
+// removed from the superposition of every border slot when the wave is
+// initialized.
+//
+// Call this before Initialize. Use Validate afterwards to make sure the
+// remaining modules still leave every slot with at least one option.
+func (w *Wave) InteriorOnly(m *Module) {
+	if w.interiorOnly == nil {
+		w.interiorOnly = make(map[*Module]bool)
+	}
+	w.interiorOnly[m] = true
+	delete(w.borderOnly, m)
+}
+
+// IsBorder returns true if the given coordinates are on the outer ring of the
+// grid.
+func (w *Wave) IsBorder(x, y int) bool {
+	return x == 0 || y == 0 || x == w.Width-1 || y == w.Height-1
+}
+
+// allowedAt returns a fresh list of the input modules that may be placed at
+// the given coordinates before any collapse takes place.
+func (w *Wave) allowedAt(x, y int) []*Module {
+	res := make([]*Module, 0, len(w.Input))
+	for _, m := range w.Input {
+		if w.placementAllows(x, y, m) {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// placementAllows checks the BorderOnly and InteriorOnly rules of a module for
+// the given coordinates, and whether its multi-tile structure fits there.
+func (w *Wave) placementAllows(x, y int, m *Module) bool {
+	if !w.multiTileFits(x, y, m) {
+		return false
+	}
+	if w.IsBorder(x, y) {
+		return !w.interiorOnly[m]
+	}
+	return !w.borderOnly[m]
+}
@@ -0,0 +1,44 @@
+package wfc
+
+import (
+	"sort"
+)
+
+// PropagationOrder decides in which order the neighbors of a slot are visited
+// while propagating constraints. The order affects which contradictions are
+// found first, and to some extent the look of the output.
+type PropagationOrder int
+
+const (
+	// PropagationOrderFixed visits neighbors in the order of Directions.
+	PropagationOrderFixed PropagationOrder = iota
+
+	// PropagationOrderMostConstrained visits the neighbor with the fewest
+	// remaining modules first. Contradictions tend to surface earlier, so bad
+	// seeds are rejected faster.
+	PropagationOrderMostConstrained
+)
+
+// propagationDirections returns the directions to visit from the given slot,
+// ordered according to the wave's propagation order.
+func (w *Wave) propagationDirections(s *Slot) []Direction {
+	if w.Propagation != PropagationOrderMostConstrained {
+		return Directions
+	}
+
+	dirs := make([]Direction, len(Directions))
+	copy(dirs, Directions)
+
+	entropy := func(d Direction) int {
+		if !w.HasNeighbor(s, d) {
+			return len(w.Input) + 1
+		}
+		return len(w.GetNeighbor(s, d).Superposition)
+	}
+
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return entropy(dirs[i]) < entropy(dirs[j])
+	})
+
+	return dirs
+}
@@ -0,0 +1,144 @@
+package wfc
+
+import "image"
+
+// Relax reduces repetition in a fully collapsed wave. Each pass visits every
+// slot and, with the given probability, swaps its module for a less used one
+// that the slot allowed after initialization (see Initialize and its
+// variants), that is still legal next to all of its neighbors and that doesn't
+// complete a forbidden pattern (see ForbidPattern). Locked slots (see
+// LockCollapsed) and the parts of multi-tile structures (see AddMultiTile) are
+// never swapped. The output stays valid, only its variety changes.
+//
+// Returns ErrNotCollapsed if the wave has uncollapsed or contradicting slots.
+func (w *Wave) Relax(prob float64, passes int) error {
+	if err := w.checkInitialized(); err != nil {
+		return err
+	}
+
+	counts := make(map[*Module]int)
+	for _, s := range w.PossibilitySpace {
+		if len(s.Superposition) != 1 {
+			return ErrNotCollapsed
+		}
+		counts[s.Superposition[0]]++
+	}
+
+	for p := 0; p < passes; p++ {
+		for i, s := range w.PossibilitySpace {
+			if w.rng.Float64() >= prob {
+				continue
+			}
+			if _, ok := w.locked[image.Pt(s.X, s.Y)]; ok {
+				continue
+			}
+
+			current := s.Superposition[0]
+			if _, ok := w.multiTileParts[current]; ok {
+				continue
+			}
+
+			allowed := w.Input
+			if w.initial != nil {
+				allowed = w.initial[i]
+			}
+			candidates := make([]*Module, 0)
+			for _, m := range allowed {
+				if m == current || counts[m] >= counts[current] {
+					continue
+				}
+				if _, ok := w.multiTileParts[m]; ok {
+					continue
+				}
+				if w.placementAllows(s.X, s.Y, m) && w.fitsNeighbors(s, m) &&
+					!w.completesPattern(s, m) {
+					candidates = append(candidates, m)
+				}
+			}
+			if len(candidates) == 0 {
+				continue
+			}
+
+			// Prefer the least used of the candidates
+			best := candidates[0]
+			for _, m := range candidates[1:] {
+				if counts[m] < counts[best] {
+					best = m
+				}
+			}
+
+			counts[current]--
+			counts[best]++
+			w.steps++
+			s.Superposition = []*Module{best}
+			s.CollapsedAt = w.steps
+			w.recordPlaced(s)
+		}
+	}
+
+	return nil
+}
+
+// fitsNeighbors checks if the module could be placed at the slot given the
+// collapsed modules of all of its neighbors, in both directions.
+func (w *Wave) fitsNeighbors(s *Slot, m *Module) bool {
+	candidate := &Slot{X: s.X, Y: s.Y, Superposition: []*Module{m}}
+	for _, d := range Directions {
+		if !w.HasNeighbor(s, d) {
+			continue
+		}
+		n := w.GetNeighbor(s, d)
+		if len(n.Superposition) != 1 {
+			continue
+		}
+		if !w.isPossible(m, n, candidate, d.Opposite()) {
+			return false
+		}
+		if !w.isPossible(n.Superposition[0], candidate, n, d) {
+			return false
+		}
+	}
+	return true
+}
+
+// completesPattern checks if placing the module at the slot would complete one
+// of the forbidden patterns with the collapsed modules around it.
+func (w *Wave) completesPattern(s *Slot, m *Module) bool {
+	for _, pattern := range w.patterns {
+		for py, row := range pattern {
+			for px, c := range row {
+				if c == m && w.matchesPattern(pattern, s.X-px, s.Y-py, s, m) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchesPattern checks if a forbidden pattern placed with its top left corner
+// at the given coordinates matches the collapsed modules, with the given slot
+// collapsed into the given module.
+func (w *Wave) matchesPattern(pattern [][]*Module, x0, y0 int, s *Slot, m *Module) bool {
+	for py, row := range pattern {
+		for px, c := range row {
+			if c == nil {
+				continue
+			}
+			o := w.patternSlot(x0+px, y0+py)
+			if o == nil {
+				return false
+			}
+			if o == s {
+				if m != c {
+					return false
+				}
+				continue
+			}
+			if len(o.Superposition) != 1 || o.Superposition[0] != c {
+				return false
+			}
+		}
+	}
+	return true
+}
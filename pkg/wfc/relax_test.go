@@ -0,0 +1,99 @@
+package wfc
+
+import (
+	"image"
+	"testing"
+)
+
+func TestRelaxKeepsOutputValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(w *Wave) error
+		check func(w *Wave) string
+	}{
+		{
+			name: "initial superposition",
+			setup: func(w *Wave) error {
+				return w.InitializeWithHints(1, map[image.Point][]*Module{{0, 0}: {w.Input[0]}})
+			},
+			check: func(w *Wave) string {
+				if w.GetSlot(0, 0).Superposition[0] != w.Input[0] {
+					return "hinted slot was swapped for a module it doesn't allow"
+				}
+				return ""
+			},
+		},
+		{
+			name: "locked slot",
+			setup: func(w *Wave) error {
+				w.Initialize(1)
+				fill(w, w.Input[0])
+				return w.LockCollapsed(image.Rect(0, 0, 1, 1))
+			},
+			check: func(w *Wave) string {
+				if w.GetSlot(0, 0).Superposition[0] != w.Input[0] {
+					return "locked slot was swapped"
+				}
+				return ""
+			},
+		},
+		{
+			name: "forbidden pattern",
+			setup: func(w *Wave) error {
+				w.ForbidPattern([][]*Module{{w.Input[1]}})
+				w.ForbidPattern([][]*Module{{w.Input[2], nil, w.Input[2]}})
+				w.Initialize(1)
+				return nil
+			},
+			check: func(w *Wave) string {
+				for _, s := range w.PossibilitySpace {
+					if s.Superposition[0] == w.Input[1] {
+						return "swap completed a single cell pattern"
+					}
+					if s.X+2 < w.Width && s.Superposition[0] == w.Input[2] &&
+						w.GetSlot(s.X+2, s.Y).Superposition[0] == w.Input[2] {
+						return "swap completed a pattern with a wildcard"
+					}
+				}
+				return ""
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		w := NewSymbolic(3, func(a, b int, d Direction) bool { return true }, 4, 4)
+		if err := tt.setup(w); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		fill(w, w.Input[0])
+		steps := w.steps
+
+		if err := w.Relax(1, 3); err != nil {
+			t.Fatalf("%s: Relax: %v", tt.name, err)
+		}
+		if msg := tt.check(w); msg != "" {
+			t.Errorf("%s: %s", tt.name, msg)
+		}
+
+		swapped := 0
+		for _, s := range w.PossibilitySpace {
+			if s.Superposition[0] != w.Input[0] {
+				swapped++
+				if s.CollapsedAt <= steps {
+					t.Errorf("%s: CollapsedAt of swapped slot %d,%d not updated", tt.name, s.X, s.Y)
+				}
+			}
+		}
+		if swapped == 0 {
+			t.Errorf("%s: no slot was swapped", tt.name)
+		}
+	}
+}
+
+// fill collapses every slot of the wave into the given module.
+func fill(w *Wave, m *Module) {
+	for _, s := range w.PossibilitySpace {
+		s.Superposition = []*Module{m}
+		s.CollapsedAt = w.steps
+	}
+}
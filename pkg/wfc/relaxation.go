@@ -0,0 +1,96 @@
+package wfc
+
+import (
+	"errors"
+)
+
+// RelaxedConstraint is an adjacency rule CollapseWithRelaxation had to ignore:
+// module B was placed next to module A in direction D although the rules don't
+// allow it.
+type RelaxedConstraint struct {
+	A, B *Module
+	D    Direction
+}
+
+// CollapseWithRelaxation collapses the wave with CollapseAuto and, if the
+// tileset is over-constrained, relaxes adjacency rules one at a time until a
+// solution is found. The rules to relax are taken from a best effort attempt
+// (see CollapseBestEffort): of the pairings it had to violate, the one between
+// the modules with the lowest combined weight is allowed first, as the least
+// important one. After every relaxed rule the wave is reset to the state it
+// had when CollapseWithRelaxation was called and collapsed again.
+//
+// The relaxed rules are recorded on the wave (see CanNeighbor) and stay in
+// effect, so the output is consistent with the rules of the wave and later
+// collapses of it allow the same pairings. The input modules are not changed,
+// so other waves sharing them keep the original rules. Returns the wave and
+// the relaxed rules in the order they were relaxed, nil if the tileset could
+// be solved as is.
+func (w *Wave) CollapseWithRelaxation() (*Wave, []RelaxedConstraint, error) {
+	if err := w.checkInitialized(); err != nil {
+		return w, nil, err
+	}
+
+	start := w.snapshot()
+	steps, log := w.steps, len(w.decisions)
+
+	var relaxed []RelaxedConstraint
+	for {
+		err := w.CollapseAuto()
+		if !errors.Is(err, ErrNoSolution) {
+			return w, relaxed, err
+		}
+
+		w.fillBestEffort()
+		c, ok := w.leastImportantViolation()
+		if !ok || containsConstraint(relaxed, c) {
+			// Nothing left to relax, or IsPossibleFn ignores the module rules
+			return w, relaxed, ErrNoSolution
+		}
+		if w.Logger != nil {
+			w.Logger.Debug("relax", "a", c.A.Index, "b", c.B.Index, "direction", c.D.ToString())
+		}
+		relaxed = append(relaxed, c)
+		w.setPairRule(c.A, c.B, c.D, true)
+
+		w.restore(start)
+		w.steps = steps
+		w.decisions = w.decisions[:log]
+	}
+}
+
+// leastImportantViolation returns the violated pairing of neighboring modules
+// with the lowest combined weight. Returns false if there is none.
+func (w *Wave) leastImportantViolation() (RelaxedConstraint, bool) {
+	var best RelaxedConstraint
+	found := false
+	for _, s := range w.PossibilitySpace {
+		if len(s.Superposition) != 1 {
+			continue
+		}
+		for _, d := range []Direction{Right, Down} {
+			if !w.HasNeighbor(s, d) {
+				continue
+			}
+			n := w.GetNeighbor(s, d)
+			if len(n.Superposition) != 1 || w.isPossible(n.Superposition[0], s, n, d) {
+				continue
+			}
+			c := RelaxedConstraint{A: s.Superposition[0], B: n.Superposition[0], D: d}
+			if !found || c.A.weight()*c.B.weight() < best.A.weight()*best.B.weight() {
+				best, found = c, true
+			}
+		}
+	}
+	return best, found
+}
+
+// containsConstraint checks if a list of relaxed rules contains the given rule.
+func containsConstraint(list []RelaxedConstraint, c RelaxedConstraint) bool {
+	for _, o := range list {
+		if o == c {
+			return true
+		}
+	}
+	return false
+}
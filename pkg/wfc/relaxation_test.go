@@ -0,0 +1,33 @@
+package wfc
+
+import "testing"
+
+func TestCollapseWithRelaxationIsPerWave(t *testing.T) {
+	// Tiles that may not be placed next to each other can't fill a 2x1 grid
+	rule := func(a, b int, d Direction) bool { return d != Left && d != Right }
+	w := NewSymbolic(2, rule, 2, 1)
+	other := NewFromModules(w.Input, 2, 1)
+	w.Initialize(1)
+
+	out, relaxed, err := w.CollapseWithRelaxation()
+	if err != nil {
+		t.Fatalf("CollapseWithRelaxation: %v", err)
+	}
+	if len(relaxed) != 1 {
+		t.Fatalf("got %d relaxed rules, want 1", len(relaxed))
+	}
+
+	a, b, d := relaxed[0].A, relaxed[0].B, relaxed[0].D
+	if !out.CanNeighbor(a, b, d) || !out.CanNeighbor(b, a, d.Opposite()) {
+		t.Error("relaxed pairing is not allowed by the returned wave")
+	}
+	if a.CanNeighbor(b, d) {
+		t.Error("relaxation changed the rules of the shared module")
+	}
+	if other.CanNeighbor(a, b, d) {
+		t.Error("relaxation leaked into another wave")
+	}
+	if c, found := out.leastImportantViolation(); found {
+		t.Errorf("returned wave violates its own rules: %+v", c)
+	}
+}
@@ -0,0 +1,93 @@
+package wfc
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+var (
+	ErrReplayMismatch = errors.New("recorded decision is not possible")
+)
+
+// Decision is a recorded collapse decision: the slot that was collapsed and
+// the position in Wave.Input of the module it was collapsed into.
+type Decision struct {
+	Slot   image.Point
+	Module int
+}
+
+// Decisions returns the decision log (see DecisionLog) along with the module
+// every decision chose, for use with Replay. Decisions of slots that are no
+// longer collapsed are left out.
+//
+// Backtracking rules out modules without making a decision, so the log alone
+// may not be enough to reconstruct the output. The remaining collapsed slots
+// are therefore appended in scan order; replaying them is a no-op for slots
+// that the earlier decisions already settle.
+func (w *Wave) Decisions() []Decision {
+	position := make(map[*Module]int, len(w.Input))
+	for i, m := range w.Input {
+		position[m] = i
+	}
+
+	res := make([]Decision, 0, len(w.decisions))
+	logged := make(map[image.Point]bool, len(w.decisions))
+	for _, p := range w.decisions {
+		s := w.GetSlot(p.X, p.Y)
+		if len(s.Superposition) != 1 {
+			continue
+		}
+		res = append(res, Decision{Slot: p, Module: position[s.Superposition[0]]})
+		logged[p] = true
+	}
+
+	for y := 0; y < w.Height; y++ {
+		for x := 0; x < w.Width; x++ {
+			s := w.GetSlot(x, y)
+			p := image.Pt(x, y)
+			if s != nil && len(s.Superposition) == 1 && !logged[p] {
+				res = append(res, Decision{Slot: p, Module: position[s.Superposition[0]]})
+			}
+		}
+	}
+	return res
+}
+
+// Replay reconstructs a collapse from recorded decisions (see Decisions). The
+// decisions are applied in order, each one propagated before the next, so the
+// result only depends on the recorded choices and not on the random source.
+// Use this to share an exact output, even across versions of this package.
+//
+// The wave must be initialized with the same size, input and rules as the one
+// the decisions were recorded on. Returns ErrReplayMismatch if a decision
+// doesn't fit the current state, which means one of those differs.
+func (w *Wave) Replay(log []Decision) error {
+	if err := w.checkInitialized(); err != nil {
+		return err
+	}
+
+	for i, d := range log {
+		inGrid := d.Slot.In(image.Rect(0, 0, w.Width, w.Height))
+		if !inGrid || d.Module < 0 || d.Module >= len(w.Input) {
+			return fmt.Errorf("decision %d: %w", i, ErrReplayMismatch)
+		}
+		s := w.GetSlot(d.Slot.X, d.Slot.Y)
+		m := w.Input[d.Module]
+		if !containsModule(s.Superposition, m) {
+			return fmt.Errorf("decision %d: module %d at %d,%d: %w",
+				i, d.Module, d.Slot.X, d.Slot.Y, ErrReplayMismatch)
+		}
+		if len(s.Superposition) == 1 {
+			// Already settled by the previous decisions
+			continue
+		}
+
+		w.decideModule(s, m)
+		if err := w.propagate(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package wfc
+
+import "image"
+
+// CollapseResilient collapses the wave without global backtracking. When a
+// decision leads to a contradiction, the neighborhood of every slot left
+// without modules is reset to its initial superposition and constrained again
+// by the slots around it, keeping the rest of the grid intact. If that fails
+// too, the neighborhood grows by one slot in every direction and is reset
+// again. This is a local repair strategy for large or endless maps, where
+// starting over is too expensive; since only the reset area is propagated
+// again, it may leave minor inconsistencies around repaired areas.
+//
+// Returns the number of local resets made. Returns ErrNoSolution once more
+// than maxLocalResets resets would be needed, in which case the wave is left
+// in the partially collapsed state.
+func (w *Wave) CollapseResilient(maxLocalResets int) (int, error) {
+	if err := w.checkInitialized(); err != nil {
+		return 0, err
+	}
+
+	resets := 0
+	for !w.IsCollapsed() {
+		slot := w.nextSlot()
+		if slot == nil {
+			break
+		}
+		w.decide(slot)
+
+		err := w.propagate(slot)
+		for radius := 1; err != nil; radius++ {
+			if resets >= maxLocalResets {
+				return resets, ErrNoSolution
+			}
+			resets++
+			err = w.resetAround(slot, radius)
+		}
+	}
+
+	return resets, nil
+}
+
+// resetAround resets the slots within the given radius of every contradiction
+// to their initial superposition and propagates the slots around them into the
+// reset area. If there is no slot without modules, e.g. because a forbidden
+// pattern was completed, the area around the given slot is reset instead.
+//
+// The initial superposition is the one the slot had right after
+// initialization, so the restrictions of hints, masks, noise fields and
+// prepopulated maps are kept. Locked slots (see LockCollapsed) keep their
+// module. The cached entropies, queued pattern checks and module counts of
+// the slots outside the area are kept.
+func (w *Wave) resetAround(origin *Slot, radius int) error {
+	centers := make([]*Slot, 0)
+	for _, s := range w.PossibilitySpace {
+		if len(s.Superposition) == 0 {
+			centers = append(centers, s)
+		}
+	}
+	if len(centers) == 0 {
+		centers = append(centers, origin)
+	}
+
+	area := make(map[*Slot]bool)
+	for _, c := range centers {
+		for dy := -radius; dy <= radius; dy++ {
+			for dx := -radius; dx <= radius; dx++ {
+				if s := w.patternSlot(c.X+dx, c.Y+dy); s != nil {
+					area[s] = true
+				}
+			}
+		}
+	}
+
+	if w.Logger != nil {
+		w.Logger.Debug("local reset", "slots", len(area), "radius", radius)
+	}
+
+	for i, s := range w.PossibilitySpace {
+		if !area[s] {
+			continue
+		}
+		if m, ok := w.locked[image.Pt(s.X, s.Y)]; ok {
+			s.Superposition = []*Module{m}
+			w.markCollapsed(s)
+			w.recordPlaced(s)
+			continue
+		}
+		w.forgetPlaced(s)
+		if w.initial != nil {
+			s.Superposition = make([]*Module, len(w.initial[i]))
+			copy(s.Superposition, w.initial[i])
+		} else {
+			s.Superposition = w.allowedAt(s.X, s.Y)
+		}
+		s.CollapsedAt = -1
+		w.updateEntropy(s)
+	}
+	w.History = make([]*Slot, 0)
+
+	// Drop the pattern checks of the reset slots, those outside the area
+	// are still due
+	queue := w.patternQueue[:0]
+	for _, s := range w.patternQueue {
+		if !area[s] {
+			queue = append(queue, s)
+		}
+	}
+	w.patternQueue = queue
+
+	// Constrain the reset area from the slots around it
+	for _, s := range w.PossibilitySpace {
+		if area[s] {
+			continue
+		}
+		for _, d := range Directions {
+			if w.HasNeighbor(s, d) && area[w.GetNeighbor(s, d)] {
+				if err := w.propagate(s); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	return nil
+}
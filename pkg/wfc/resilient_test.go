@@ -0,0 +1,54 @@
+package wfc
+
+import "testing"
+
+func TestResetAroundKeepsStateOutsideArea(t *testing.T) {
+	w := NewSymbolic(2, func(a, b int, d Direction) bool { return true }, 5, 5)
+	m0, m1 := w.Input[0], w.Input[1]
+	w.ForbidPattern([][]*Module{{m0, m1}})
+	w.Initialize(1)
+	for _, s := range w.PossibilitySpace {
+		s.Superposition = []*Module{w.Input[(s.X+s.Y)%2]}
+		s.CollapsedAt = 0
+	}
+	center := w.GetSlot(2, 2)
+	center.Superposition = nil
+
+	w.countPlaced()
+	w.entropy = w.newEntropyHeap()
+	// The check of the slot left of the area still applies to the reset
+	// slots, the one of the slot inside the area is dropped
+	w.patternQueue = []*Slot{w.GetSlot(0, 2), w.GetSlot(1, 1)}
+
+	if err := w.resetAround(center, 1); err != nil {
+		t.Fatalf("resetAround: %v", err)
+	}
+
+	for _, s := range w.PossibilitySpace {
+		want := 1
+		if s.X >= 1 && s.X <= 3 && s.Y >= 1 && s.Y <= 3 && s.Y != 2 {
+			want = 2
+		}
+		if got := len(s.Superposition); got != want {
+			t.Errorf("slot %d,%d has %d modules after reset, want %d", s.X, s.Y, got, want)
+		}
+	}
+	for x := 1; x <= 3; x++ {
+		if s := w.GetSlot(x, 2); len(s.Superposition) != 1 || s.Superposition[0] != m0 {
+			t.Errorf("slot %d,2 was not constrained by the queued pattern check", x)
+		}
+	}
+	if w.entropy == nil {
+		t.Error("entropy heap was dropped")
+	} else if w.lowestEntropySlot() == nil {
+		t.Error("entropy heap has no entries for the reset slots")
+	}
+
+	counts := w.placedCount
+	w.countPlaced()
+	for _, m := range []*Module{m0, m1} {
+		if counts[m] != w.placedCount[m] {
+			t.Errorf("module %d counted %d times, want %d", m.Index, counts[m], w.placedCount[m])
+		}
+	}
+}
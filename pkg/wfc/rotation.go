@@ -0,0 +1,325 @@
+package wfc
+
+import (
+	"fmt"
+	"image"
+)
+
+// RotateModule returns a copy of the given module rotated clockwise by the
+// given number of quarter turns. Negative values rotate counter-clockwise.
+//
+// The image is rotated and the adjacency constraints are remapped to the
+// directions they end up facing, e.g. after a single quarter turn the old Left
+// constraint becomes the new Up constraint.
+//
+// Note that the remap moves constraints between directions but does not
+// re-read the pixels. With the default color sampling an edge that isn't
+// symmetric reads in reverse after rotation, so its constraint changes too.
+// ExpandRotations takes care of this by recomputing the constraints with the
+// wave's ConstraintFn.
+//
+// Rotating a reflected module turns it the other way relative to its source,
+// so its Rotation decreases by quarters instead (see Module.Rotation).
+func RotateModule(m *Module, quarters int) *Module {
+	q := ((quarters % 4) + 4) % 4
+
+	source := m.Source
+	if source == nil {
+		source = m
+	}
+
+	rotated := &Module{
+		Index:       m.Index,
+		Adjacencies: m.Adjacencies,
+		Image:       m.Image,
+		Weight:      m.Weight,
+		Rotation:    rotatedBy(m, q),
+		Reflected:   m.Reflected,
+		Source:      source,
+		Group:       m.Group,
+		Tags:        m.Tags,
+	}
+
+	for i := 0; i < q; i++ {
+		a := rotated.Adjacencies
+		rotated.Adjacencies[Up] = a[Left]
+		rotated.Adjacencies[Right] = a[Up]
+		rotated.Adjacencies[Down] = a[Right]
+		rotated.Adjacencies[Left] = a[Down]
+	}
+
+	if m.Image != nil {
+		rotated.Image = rotateImage(m.Image, q)
+	}
+
+	return rotated
+}
+
+// ReflectModule returns a copy of the given module mirrored horizontally. The
+// image is flipped and the Left and Right adjacency constraints are swapped.
+//
+// As with RotateModule, the Up and Down edges read in reverse after the flip,
+// which ExpandReflections accounts for by recomputing the constraints with the
+// wave's ConstraintFn.
+func ReflectModule(m *Module) *Module {
+	source := m.Source
+	if source == nil {
+		source = m
+	}
+
+	reflected := &Module{
+		Index:       m.Index,
+		Adjacencies: m.Adjacencies,
+		Image:       m.Image,
+		Weight:      m.Weight,
+		Rotation:    m.Rotation,
+		Reflected:   !m.Reflected,
+		Source:      source,
+		Group:       m.Group,
+		Tags:        m.Tags,
+	}
+	reflected.Adjacencies[Left] = m.Adjacencies[Right]
+	reflected.Adjacencies[Right] = m.Adjacencies[Left]
+
+	if m.Image != nil {
+		reflected.Image = reflectImage(m.Image)
+	}
+
+	return reflected
+}
+
+// ExpandRotations adds the three rotated variants of every input module to
+// the wave's input. The new modules are appended after the original ones and
+// their Index is set to their position in Input.
+//
+// Tiles for which locked returns true (given their index in Input) are not
+// rotated, e.g. arrows or text that only make sense one way up. A nil locked
+// func rotates every tile.
+//
+// If the wave has a ConstraintFn, the constraints of the variants are derived
+// from their rotated images. Otherwise the remapped constraints from
+// RotateModule are kept, which is what you want for hand-assigned sockets.
+//
+// Variants that are identical to a previous rotation of the same tile, both in
+// image (see DetectSymmetry) and constraints, are skipped. A fully symmetric
+// tile is therefore not expanded at all.
+//
+// Call this before Initialize.
+func (w *Wave) ExpandRotations(locked func(i int) bool) {
+	originals := w.Input
+	periods := make([]int, len(originals))
+	variants := make([][4]*Module, len(originals))
+	for i, m := range originals {
+		periods[i] = rotationPeriod(m)
+		variants[i][0] = m
+	}
+
+	for q := 1; q < 4; q++ {
+		for i, m := range originals {
+			if locked != nil && locked(i) {
+				continue
+			}
+			v := RotateModule(m, q)
+			w.deriveConstraints(v)
+			if q >= periods[i] && v.Adjacencies == variants[i][q%periods[i]].Adjacencies {
+				continue
+			}
+			variants[i][q] = v
+			w.appendVariant(v)
+		}
+	}
+}
+
+// ExpandReflections adds a horizontally mirrored variant of every input module
+// to the wave's input. It works like ExpandRotations, including the locked
+// predicate, and can be combined with it to produce all eight orientations.
+// Either order yields the same set of variants, since Rotation and Reflected
+// always describe the source rotated first and mirrored second.
+//
+// Call this before Initialize.
+func (w *Wave) ExpandReflections(locked func(i int) bool) {
+	originals := w.Input
+	for i, m := range originals {
+		if locked != nil && locked(i) {
+			continue
+		}
+		w.addVariant(ReflectModule(m))
+	}
+}
+
+// addVariant appends a derived module to the input, recomputing its
+// constraints from its image if the wave has a constraint function.
+func (w *Wave) addVariant(m *Module) {
+	w.deriveConstraints(m)
+	w.appendVariant(m)
+}
+
+// appendVariant appends a derived module whose constraints are already final
+// to the input.
+func (w *Wave) appendVariant(m *Module) {
+	m.Index = len(w.Input)
+	w.Input = append(w.Input, m)
+}
+
+// deriveConstraints recomputes the constraints of a module from its image if
+// the wave has a constraint function.
+func (w *Wave) deriveConstraints(m *Module) {
+	if w.ConstraintFn != nil && m.Image != nil {
+		for _, d := range Directions {
+			m.Adjacencies[d] = w.ConstraintFn(m.Image, d)
+		}
+	}
+}
+
+// rotateImage rotates an image clockwise by the given number of quarter turns.
+func rotateImage(img image.Image, quarters int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	q := ((quarters % 4) + 4) % 4
+
+	var out *image.RGBA
+	if q%2 == 1 {
+		out = image.NewRGBA(image.Rect(0, 0, h, w))
+	} else {
+		out = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			c := img.At(b.Min.X+x, b.Min.Y+y)
+			switch q {
+			case 0:
+				out.Set(x, y, c)
+			case 1:
+				out.Set(h-1-y, x, c)
+			case 2:
+				out.Set(w-1-x, h-1-y, c)
+			case 3:
+				out.Set(y, w-1-x, c)
+			}
+		}
+	}
+
+	return out
+}
+
+// reflectImage mirrors an image horizontally.
+func reflectImage(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			out.Set(w-1-x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	return out
+}
+
+// variantKey identifies a rotated and/or reflected variant of a source tile.
+type variantKey struct {
+	source    *Module
+	rotation  int
+	reflected bool
+}
+
+// RotateOutput rotates the whole grid clockwise by the given number of quarter
+// turns, without collapsing it again. The slots are moved to their rotated
+// positions and every module is swapped for its correctly rotated variant,
+// so the input must contain those variants (see ExpandRotations). Symmetric
+// tiles may use a variant with an equivalent rotation instead. For odd quarter
+// turns the width and height of the wave are swapped.
+//
+// The rotated grid is only guaranteed to be consistent if the constraints
+// don't depend on the orientation of the tile, e.g. NewFullEdgeConstraintFunc
+// or hand-assigned sockets. The default constraint function samples edges at
+// positions that aren't symmetric, so rotated neighbors may not match.
+//
+// Returns ErrUnknownModule if a module has no variant with the required
+// rotation, in which case the wave is left unchanged.
+func (w *Wave) RotateOutput(quarters int) error {
+	q := ((quarters % 4) + 4) % 4
+	if q == 0 {
+		return nil
+	}
+
+	variants := make(map[variantKey]*Module, len(w.Input))
+	periods := make(map[*Module]int)
+	for _, m := range w.Input {
+		variants[variantKeyOf(m, m.Rotation)] = m
+	}
+
+	width, height := w.Width, w.Height
+	if q%2 == 1 {
+		width, height = w.Height, w.Width
+	}
+
+	space := make([]*Slot, len(w.PossibilitySpace))
+	for _, s := range w.PossibilitySpace {
+		x, y := s.X, s.Y
+		for i := 0; i < q; i++ {
+			// One clockwise quarter turn of a grid that is h slots high
+			h := w.Height
+			if i%2 == 1 {
+				h = w.Width
+			}
+			x, y = h-1-y, x
+		}
+
+		rotated := &Slot{
+			X: x, Y: y,
+			Superposition: make([]*Module, len(s.Superposition)),
+			CollapsedAt:   s.CollapsedAt,
+		}
+		for i, m := range s.Superposition {
+			r := rotatedBy(m, q)
+			v, ok := variants[variantKeyOf(m, r)]
+			if !ok {
+				// Redundant rotations of symmetric tiles are not expanded
+				source := variantKeyOf(m, 0).source
+				if _, known := periods[source]; !known {
+					periods[source] = rotationPeriod(source)
+				}
+				v, ok = variants[variantKeyOf(m, r%periods[source])]
+			}
+			if !ok {
+				return fmt.Errorf("module %d rotated by %d: %w", m.Index, q, ErrUnknownModule)
+			}
+			rotated.Superposition[i] = v
+		}
+		space[x+y*width] = rotated
+	}
+
+	w.Width, w.Height = width, height
+	w.PossibilitySpace = space
+	w.History = make([]*Slot, 0)
+	w.entropy = nil
+	w.importance = nil
+	w.placed = nil
+	w.placedCount = nil
+
+	return nil
+}
+
+// variantKeyOf returns the key of the variant of a module's source tile with
+// the given rotation and the module's reflection.
+func variantKeyOf(m *Module, rotation int) variantKey {
+	source := m.Source
+	if source == nil {
+		source = m
+	}
+	return variantKey{source: source, rotation: ((rotation % 4) + 4) % 4, reflected: m.Reflected}
+}
+
+// rotatedBy returns the Rotation of a module after turning it clockwise by the
+// given number of quarter turns. Mirroring reverses the direction of any
+// later rotation, so reflected modules count down instead of up.
+func rotatedBy(m *Module, quarters int) int {
+	if m.Reflected {
+		quarters = -quarters
+	}
+	return (((m.Rotation + quarters) % 4) + 4) % 4
+}
@@ -0,0 +1,93 @@
+package wfc
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// asymmetricTile returns a 3x3 tile shaped like the letter F, which no
+// rotation or reflection leaves unchanged.
+func asymmetricTile() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	ink := color.RGBA{255, 0, 0, 255}
+	for _, p := range []image.Point{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {1, 1}, {0, 2}} {
+		img.Set(p.X, p.Y, ink)
+	}
+	return img
+}
+
+// orientedImage renders the source tile in the orientation described by the
+// Rotation and Reflected fields of m.
+func orientedImage(src image.Image, m *Module) image.Image {
+	img := rotateImage(src, m.Rotation)
+	if m.Reflected {
+		img = reflectImage(img)
+	}
+	return img
+}
+
+func TestVariantOrientation(t *testing.T) {
+	src := asymmetricTile()
+	for _, order := range []string{"rotate first", "reflect first"} {
+		w := NewFromModules([]*Module{{Image: src}}, 1, 1)
+		if order == "rotate first" {
+			w.ExpandRotations(nil)
+			w.ExpandReflections(nil)
+		} else {
+			w.ExpandReflections(nil)
+			w.ExpandRotations(nil)
+		}
+
+		if len(w.Input) != 8 {
+			t.Fatalf("%s: got %d variants, want 8", order, len(w.Input))
+		}
+		seen := make(map[variantKey]bool)
+		for _, m := range w.Input {
+			if !imagesEqual(m.Image, orientedImage(src, m)) {
+				t.Errorf("%s: image of variant %d doesn't match rotation %d, reflected %v",
+					order, m.Index, m.Rotation, m.Reflected)
+			}
+			seen[variantKeyOf(m, m.Rotation)] = true
+		}
+		if len(seen) != 8 {
+			t.Errorf("%s: got %d distinct orientations, want 8", order, len(seen))
+		}
+	}
+}
+
+func TestRotateOutputReflected(t *testing.T) {
+	w := NewFromModules([]*Module{{Image: asymmetricTile()}}, 4, 2)
+	w.ExpandRotations(nil)
+	w.ExpandReflections(nil)
+	w.Initialize(1)
+
+	before := make(map[image.Point]image.Image)
+	for i, s := range w.PossibilitySpace {
+		m := w.Input[i%len(w.Input)]
+		s.Superposition = []*Module{m}
+		before[image.Pt(s.X, s.Y)] = m.Image
+	}
+
+	for q := 1; q < 4; q++ {
+		if err := w.RotateOutput(1); err != nil {
+			t.Fatalf("RotateOutput: %v", err)
+		}
+
+		rotated := make(map[image.Point]image.Image)
+		for p, img := range before {
+			// One clockwise quarter turn of the grid before this rotation
+			h := w.Width
+			rotated[image.Pt(h-1-p.Y, p.X)] = rotateImage(img, 1)
+		}
+		for _, s := range w.PossibilitySpace {
+			want := rotated[image.Pt(s.X, s.Y)]
+			got := s.Superposition[0]
+			if !imagesEqual(got.Image, want) {
+				t.Errorf("after %d turns: slot %d,%d holds rotation %d, reflected %v, which doesn't match the rotated tile",
+					q, s.X, s.Y, got.Rotation, got.Reflected)
+			}
+		}
+		before = rotated
+	}
+}
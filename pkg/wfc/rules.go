@@ -0,0 +1,43 @@
+package wfc
+
+// rulePair is a pairing of module "b" next to module "a" in direction d.
+type rulePair struct {
+	a, b *Module
+	d    Direction
+}
+
+// CanNeighbor returns true if module "b" may be placed next to module "a" in
+// the given direction in this wave. It is like Module.CanNeighbor, but also
+// honors the rules that only apply to this wave, such as those set with
+// ConnectEdgeLevels. These rules override the ones recorded on the modules with
+// Forbid and Allow, which are shared by every wave using the modules.
+func (w *Wave) CanNeighbor(a, b *Module, d Direction) bool {
+	if ok, found := w.pairRules[rulePair{a, b, d}]; found {
+		return ok
+	}
+	return a.CanNeighbor(b, d)
+}
+
+// allowPair allows module "b" next to module "a" in the given direction for
+// this wave, unless the pairing is ruled out with Forbid or by the wave.
+func (w *Wave) allowPair(a, b *Module, d Direction) {
+	if a.forbidden[d][b] {
+		return
+	}
+	if ok, found := w.pairRules[rulePair{a, b, d}]; found && !ok {
+		return
+	}
+	w.setPairRule(a, b, d, true)
+}
+
+// setPairRule allows or forbids module "b" next to module "a" in the given
+// direction for this wave only, overriding the rules of the modules. Like
+// Forbid and Allow, the rule is recorded for both directions of propagation.
+func (w *Wave) setPairRule(a, b *Module, d Direction, allowed bool) {
+	if w.pairRules == nil {
+		w.pairRules = make(map[rulePair]bool)
+	}
+	w.pairRules[rulePair{a, b, d}] = allowed
+	w.pairRules[rulePair{b, a, d.Opposite()}] = allowed
+	w.rulesChanged()
+}
@@ -0,0 +1,206 @@
+package wfc
+
+import (
+	"fmt"
+	"image"
+)
+
+// SelectionStrategy decides which uncollapsed slot is collapsed next.
+type SelectionStrategy int
+
+const (
+	// SelectionStrategyRandom picks any uncollapsed slot at random. This is
+	// the default and matches the original behaviour of the package.
+	SelectionStrategyRandom SelectionStrategy = iota
+
+	// SelectionStrategyLowestEntropy picks the uncollapsed slot with the
+	// fewest remaining modules, which tends to produce fewer contradictions.
+	SelectionStrategyLowestEntropy
+
+	// SelectionStrategyFrontier picks the uncollapsed slot closest (by
+	// Manhattan distance) to an already collapsed slot, which grows the
+	// output as a smooth, contiguous frontier around the first collapse.
+	SelectionStrategyFrontier
+
+	// SelectionStrategyScanline picks the first uncollapsed slot in row-major
+	// order (left to right, top to bottom). This is deterministic apart from
+	// the module choices and gives the output a directional grain, which is
+	// useful when revealing the output progressively.
+	SelectionStrategyScanline
+
+	// SelectionStrategyLocal picks an uncollapsed neighbor of the most
+	// recently collapsed slot, preferring the one with the fewest remaining
+	// modules. If that slot has no uncollapsed neighbors, it falls back to
+	// the neighbors of earlier decisions, and only jumps to a random slot
+	// once none are left. This grows coherent regions one at a time.
+	SelectionStrategyLocal
+)
+
+// SlotSelectionFunc chooses the uncollapsed slot that is collapsed next, see
+// Wave.SelectSlotFn. Returning nil lets the selection strategy pick instead.
+type SlotSelectionFunc func(w *Wave) *Slot
+
+// collapseNextSlot picks the next slot according to the selection strategy and
+// collapses it. Returns nil if there is nothing left to collapse.
+func (w *Wave) collapseNextSlot() *Slot {
+	slot := w.nextSlot()
+	if slot == nil {
+		return nil
+	}
+	w.decide(slot)
+	return slot
+}
+
+// nextSlot picks the next slot to collapse according to SelectSlotFn or the
+// selection strategy, without collapsing it. Returns nil if there is nothing
+// left to collapse.
+func (w *Wave) nextSlot() *Slot {
+	if w.start != nil && len(w.decisions) == 0 {
+		if slot := w.GetSlot(w.start.X, w.start.Y); len(slot.Superposition) > 1 {
+			return slot
+		}
+	}
+
+	if w.SelectSlotFn != nil {
+		if slot := w.SelectSlotFn(w); w.ownsSlot(slot) && len(slot.Superposition) > 1 {
+			return slot
+		}
+	}
+
+	switch w.Selection {
+	case SelectionStrategyLowestEntropy:
+		return w.lowestEntropySlot()
+	case SelectionStrategyFrontier:
+		if slot := w.frontierSlot(); slot != nil {
+			return slot
+		}
+		return w.randomSlot()
+	case SelectionStrategyScanline:
+		return w.scanlineSlot()
+	case SelectionStrategyLocal:
+		if slot := w.localSlot(); slot != nil {
+			return slot
+		}
+		return w.randomSlot()
+	default:
+		return w.randomSlot()
+	}
+}
+
+// ownsSlot checks if the given slot is part of the wave's possibility space.
+func (w *Wave) ownsSlot(s *Slot) bool {
+	if s == nil || s.X < 0 || s.X >= w.Width || s.Y < 0 || s.Y >= w.Height {
+		return false
+	}
+	return w.GetSlot(s.X, s.Y) == s
+}
+
+// frontierSlot returns a random uncollapsed slot among those closest to the set
+// of collapsed slots, or nil if nothing has been collapsed yet.
+//
+// The distances are found with a breadth-first search starting from every
+// collapsed slot at once, which on a grid yields the Manhattan distance.
+func (w *Wave) frontierSlot() *Slot {
+	visited := make(map[*Slot]bool)
+	queue := make([]*Slot, 0)
+	for _, s := range w.PossibilitySpace {
+		if len(s.Superposition) == 1 {
+			visited[s] = true
+			queue = append(queue, s)
+		}
+	}
+
+	for len(queue) > 0 {
+		found := make([]*Slot, 0)
+		next := make([]*Slot, 0)
+		for _, s := range queue {
+			for _, d := range Directions {
+				if !w.HasNeighbor(s, d) {
+					continue
+				}
+				n := w.GetNeighbor(s, d)
+				if visited[n] {
+					continue
+				}
+				visited[n] = true
+				if len(n.Superposition) > 1 {
+					found = append(found, n)
+				}
+				next = append(next, n)
+			}
+		}
+		if len(found) > 0 {
+			return found[w.rng.Intn(len(found))]
+		}
+		queue = next
+	}
+
+	return nil
+}
+
+// scanlineSlot returns the first uncollapsed slot in row-major order, or nil if
+// every slot is collapsed.
+func (w *Wave) scanlineSlot() *Slot {
+	for y := 0; y < w.Height; y++ {
+		for x := 0; x < w.Width; x++ {
+			slot := w.GetSlot(x, y)
+			if len(slot.Superposition) > 1 {
+				return slot
+			}
+		}
+	}
+	return nil
+}
+
+// localSlot returns the uncollapsed neighbor with the lowest entropy of the most
+// recent decision that has one, ties broken at random. Returns nil if no
+// decision has uncollapsed neighbors.
+func (w *Wave) localSlot() *Slot {
+	for i := len(w.decisions) - 1; i >= 0; i-- {
+		p := w.decisions[i]
+		s := w.GetSlot(p.X, p.Y)
+
+		best := make([]*Slot, 0, len(Directions))
+		for _, d := range Directions {
+			if !w.HasNeighbor(s, d) {
+				continue
+			}
+			n := w.GetNeighbor(s, d)
+			if len(n.Superposition) <= 1 {
+				continue
+			}
+			if len(best) > 0 && len(n.Superposition) > len(best[0].Superposition) {
+				continue
+			}
+			if len(best) > 0 && len(n.Superposition) < len(best[0].Superposition) {
+				best = best[:0]
+			}
+			best = append(best, n)
+		}
+		if len(best) > 0 {
+			return best[w.rng.Intn(len(best))]
+		}
+	}
+	return nil
+}
+
+// SetStartSlot makes the first decision of the collapse collapse the slot at
+// the given coordinates into the given module, instead of a random slot into a
+// random module. This removes the biggest source of variation between runs,
+// e.g. for demos. With a nil module only the position is fixed. The setting is
+// kept across Initialize.
+//
+// Returns ErrUnknownModule if the module is not part of the input.
+func (w *Wave) SetStartSlot(x, y int, m *Module) error {
+	if x < 0 || y < 0 || x >= w.Width || y >= w.Height {
+		return fmt.Errorf("start slot %d,%d is outside of the %dx%d grid", x, y, w.Width, w.Height)
+	}
+	if m != nil && !containsModule(w.Input, m) {
+		return ErrUnknownModule
+	}
+
+	p := image.Pt(x, y)
+	w.start = &p
+	w.startModule = m
+	return nil
+}
@@ -0,0 +1,46 @@
+package wfc
+
+import (
+	"image"
+)
+
+// SimilarityTo compares the output of ExportImage with a reference image,
+// e.g. to measure how close a guided collapse came to its target. It returns
+// 1 minus the mean absolute difference of all RGBA channels over all pixels,
+// so 1 means the images are identical and 0 means every channel is as far off
+// as it can be.
+//
+// If the reference has a different size than the output, it is scaled to the
+// output size with nearest neighbor sampling, so a small sketch of the
+// desired map can be used as the reference. Returns 0 for an empty output or
+// reference.
+func (w *Wave) SimilarityTo(reference image.Image) float64 {
+	out := w.ExportImage()
+	ob, rb := out.Bounds(), reference.Bounds()
+	if ob.Empty() || rb.Empty() {
+		return 0
+	}
+
+	diff := 0.0
+	for y := 0; y < ob.Dy(); y++ {
+		ry := rb.Min.Y + y*rb.Dy()/ob.Dy()
+		for x := 0; x < ob.Dx(); x++ {
+			rx := rb.Min.X + x*rb.Dx()/ob.Dx()
+			r1, g1, b1, a1 := out.At(ob.Min.X+x, ob.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := reference.At(rx, ry).RGBA()
+			diff += channelDiff(r1, r2) + channelDiff(g1, g2) +
+				channelDiff(b1, b2) + channelDiff(a1, a2)
+		}
+	}
+
+	return 1 - diff/float64(4*ob.Dx()*ob.Dy())
+}
+
+// channelDiff returns the absolute difference of two 16-bit color channels,
+// normalized to 0..1.
+func channelDiff(a, b uint32) float64 {
+	if a > b {
+		return float64(a-b) / 0xffff
+	}
+	return float64(b-a) / 0xffff
+}
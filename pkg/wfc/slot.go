@@ -15,21 +15,73 @@ import (
 type Slot struct {
 	X, Y          int       // Coordinates of the slot
 	Superposition []*Module // Possible modules at the slot
+
+	// The collapse step at which the slot was decided, either by collapsing
+	// it directly or through propagation. Slots that start out collapsed have
+	// a value of 0, slots that are not collapsed yet -1. This is metadata only
+	// and does not affect the algorithm.
+	CollapsedAt int
 }
 
 // Collapse chooses a random module from the list of superpositions available to
 // it. Its superposition list is set to the single module chosen.
+//
+// This uses the global random source. The wave uses its own seeded source
+// instead, so that waves can be collapsed concurrently.
 func (s *Slot) Collapse() {
 	module := s.Superposition[rand.Intn(len(s.Superposition))]
 	s.Superposition = []*Module{module}
 }
 
+// collapseWith is like Collapse but draws from the given random source.
+func (s *Slot) collapseWith(rng *rand.Rand) {
+	module := s.Superposition[rng.Intn(len(s.Superposition))]
+	s.Superposition = []*Module{module}
+}
+
 // IsPossibleFunc is a function that returns whether or not a module is possible
 // given a slot and direction. Use this if you'd like custom logic.
 type IsPossibleFunc func(state *Module, from, to *Slot, d Direction) bool
 
 // DefaultIsPossibleFunc returns whether or not a module is possible given a
-// slot and direction.
+// slot and direction. This is what a wave checks when its IsPossibleFn is nil,
+// so custom functions can fall back to it.
 func DefaultIsPossibleFunc(state *Module, from, to *Slot, d Direction) bool {
 	return state.IsPossibleFrom(from, d)
 }
+
+// AllPossibleFunc always returns true, ignoring adjacency constraints entirely.
+//
+// Unlike DefaultIsPossibleFunc, which only keeps modules whose edges match a
+// module in the neighboring slot, this lets any module be placed next to any
+// other. The wave then collapses into a purely random fill of the input tiles,
+// which is handy as a baseline or for tilesets where adjacency is irrelevant.
+func AllPossibleFunc(state *Module, from, to *Slot, d Direction) bool {
+	return true
+}
+
+// SelectionFunc chooses the module a slot collapses into, see Wave.SelectionFn.
+// Returning nil lets the wave pick a random module instead.
+type SelectionFunc func(s *Slot) *Module
+
+// SequenceSelection returns a selection function that replays a predetermined
+// list of choices, one per collapse decision. Each choice is an index into the
+// superposition of the slot being collapsed. Once the list is exhausted, or for
+// choices that are out of range, the wave falls back to a random pick.
+//
+// This makes a reported collapse path reproducible, e.g. "collapsing with
+// these choices contradicts at step 7".
+func SequenceSelection(choices []int) SelectionFunc {
+	next := 0
+	return func(s *Slot) *Module {
+		if next >= len(choices) {
+			return nil
+		}
+		c := choices[next]
+		next++
+		if c < 0 || c >= len(s.Superposition) {
+			return nil
+		}
+		return s.Superposition[c]
+	}
+}
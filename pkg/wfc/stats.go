@@ -0,0 +1,99 @@
+package wfc
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds performance counters of the propagation, collected while
+// CollectStats is enabled. See Wave.Stats.
+type Stats struct {
+	Steps           int           // Number of collapse steps taken
+	PropagationTime time.Duration // Time spent propagating, including slot selection in Step
+	IsPossibleCalls int64         // Number of adjacency checks made, memoized results don't count
+	MaxDepth        int           // Longest propagation path, or most rounds with ParallelPropagation
+}
+
+// Stats returns the counters collected since the wave was initialized. They
+// are only updated while CollectStats is enabled.
+func (w *Wave) Stats() Stats {
+	s := w.stats
+	s.Steps = w.steps
+	return s
+}
+
+// startStats returns the start time of a timed section, see stopStats.
+func (w *Wave) startStats() time.Time {
+	if !w.CollectStats {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// stopStats adds the time since start to the propagation time.
+func (w *Wave) stopStats(start time.Time) {
+	if w.CollectStats {
+		w.stats.PropagationTime += time.Since(start)
+	}
+}
+
+// countChecks adds n adjacency checks. It is safe for concurrent use.
+func (w *Wave) countChecks(n int) {
+	if w.CollectStats {
+		atomic.AddInt64(&w.stats.IsPossibleCalls, int64(n))
+	}
+}
+
+// recordDepth records the depth of the current propagation path.
+func (w *Wave) recordDepth(depth int) {
+	if w.CollectStats && depth > w.stats.MaxDepth {
+		w.stats.MaxDepth = depth
+	}
+}
+
+// EntropyStat summarizes the entropy of the uncollapsed slots at one point of
+// the collapse. The entropy of a slot is the number of modules left in its
+// superposition.
+type EntropyStat struct {
+	Uncollapsed int     // Number of slots with more than one module left
+	Mean        float64 // Average entropy of the uncollapsed slots
+	Min, Max    int     // Lowest and highest entropy of the uncollapsed slots
+}
+
+// EntropyHistory returns the entropy of the grid after every Step since the
+// wave was initialized, in order, e.g. to plot how quickly the collapse
+// converges and where it stalls. It is only recorded while RecordEntropy is
+// enabled. Once the wave is collapsed, the last entry has no uncollapsed
+// slots and all other fields are 0.
+func (w *Wave) EntropyHistory() []EntropyStat {
+	return append([]EntropyStat(nil), w.entropyHistory...)
+}
+
+// recordEntropy appends the current entropy of the grid to the history.
+func (w *Wave) recordEntropy() {
+	if !w.RecordEntropy {
+		return
+	}
+
+	var stat EntropyStat
+	total := 0
+	for _, s := range w.PossibilitySpace {
+		n := len(s.Superposition)
+		if n <= 1 {
+			continue
+		}
+		if stat.Uncollapsed == 0 || n < stat.Min {
+			stat.Min = n
+		}
+		if n > stat.Max {
+			stat.Max = n
+		}
+		stat.Uncollapsed++
+		total += n
+	}
+	if stat.Uncollapsed > 0 {
+		stat.Mean = float64(total) / float64(stat.Uncollapsed)
+	}
+
+	w.entropyHistory = append(w.entropyHistory, stat)
+}
@@ -0,0 +1,152 @@
+package wfc
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+var (
+	ErrEdgeMismatch = errors.New("edge lengths of the waves differ")
+)
+
+// CollapseAgainst pins the border slots on the given edge of the wave to the
+// tiles found at the same cells of an existing image, then collapses the rest
+// of the wave. Use this to stitch generated content against hand-authored
+// content so the two blend seamlessly.
+//
+// The existing image must cover the same grid as the wave (Width x Height
+// tiles of the input tile size). Only the row or column of cells on the given
+// edge is read. Each of those cells is matched to an input tile by comparing
+// pixels, cells without an exact match cause an error.
+//
+// The wave must be initialized before calling this.
+func (w *Wave) CollapseAgainst(existing image.Image, edge Direction) error {
+	if err := w.checkInitialized(); err != nil {
+		return err
+	}
+
+	u, v, err := w.TileSize()
+	if err != nil {
+		return err
+	}
+
+	cells := w.edgeCells(edge)
+
+	// Match every border cell first so we fail before touching the wave.
+	pinned := make([]*Module, len(cells))
+	for i, p := range cells {
+		tile, err := GetTileFromSpriteSheet(existing, p.X, p.Y, u, v)
+		if err != nil {
+			return err
+		}
+		for _, m := range w.Input {
+			if imagesEqual(tile, m.Image) {
+				pinned[i] = m
+				break
+			}
+		}
+		if pinned[i] == nil {
+			return fmt.Errorf("no matching image in the tileset for the %s "+
+				"border tile at %d,%d", edge.ToString(), p.X, p.Y)
+		}
+	}
+
+	for i, p := range cells {
+		slot := w.GetSlot(p.X, p.Y)
+		slot.Superposition = []*Module{pinned[i]}
+		slot.CollapsedAt = w.steps
+		w.recordPlaced(slot)
+	}
+
+	for _, p := range cells {
+		if err := w.propagate(w.GetSlot(p.X, p.Y)); err != nil {
+			return err
+		}
+	}
+
+	return w.collapseAll()
+}
+
+// ConstrainEdgeFrom restricts the slots on the given edge of the wave to the
+// modules that fit next to the slots on the opposite edge of a neighboring
+// wave, then propagates the change. For example with Up, the top row of the
+// wave is constrained by the bottom row of the neighbor, as if the neighbor
+// was placed right above it. Use this to generate chunks that continue a
+// separately generated one; the wave can be collapsed afterwards.
+//
+// Both waves should share their input modules, or at least their adjacency
+// constraints. The neighbor is usually collapsed, but any superposition is
+// taken into account. Returns ErrEdgeMismatch if the edges differ in length
+// and ErrNoSolution if an edge slot is left without modules.
+//
+// The wave must be initialized before calling this.
+func (w *Wave) ConstrainEdgeFrom(neighbor *Wave, edge Direction) error {
+	if err := w.checkInitialized(); err != nil {
+		return err
+	}
+	if err := neighbor.checkInitialized(); err != nil {
+		return err
+	}
+
+	cells := w.edgeCells(edge)
+	theirs := neighbor.edgeCells(edge.Opposite())
+	if len(cells) != len(theirs) {
+		return fmt.Errorf("%d and %d slots: %w", len(cells), len(theirs), ErrEdgeMismatch)
+	}
+
+	changed := make([]*Slot, 0)
+	for i, p := range cells {
+		slot := w.GetSlot(p.X, p.Y)
+		from := neighbor.GetSlot(theirs[i].X, theirs[i].Y)
+
+		res := make([]*Module, 0, len(slot.Superposition))
+		for _, m := range slot.Superposition {
+			if w.isPossible(m, from, slot, edge.Opposite()) {
+				res = append(res, m)
+			}
+		}
+		if len(res) == 0 {
+			return ErrNoSolution
+		}
+		if len(res) != len(slot.Superposition) {
+			slot.Superposition = res
+			w.updateEntropy(slot)
+			w.markCollapsed(slot)
+			changed = append(changed, slot)
+		}
+	}
+
+	for _, s := range changed {
+		if err := w.propagate(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// edgeCells returns the coordinates of the slots on the given edge of the
+// wave, from left to right or top to bottom.
+func (w *Wave) edgeCells(edge Direction) []image.Point {
+	var cells []image.Point
+	switch edge {
+	case Up, Down:
+		y := 0
+		if edge == Down {
+			y = w.Height - 1
+		}
+		for x := 0; x < w.Width; x++ {
+			cells = append(cells, image.Pt(x, y))
+		}
+	case Left, Right:
+		x := 0
+		if edge == Right {
+			x = w.Width - 1
+		}
+		for y := 0; y < w.Height; y++ {
+			cells = append(cells, image.Pt(x, y))
+		}
+	}
+	return cells
+}
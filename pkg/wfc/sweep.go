@@ -0,0 +1,100 @@
+package wfc
+
+import (
+	"runtime"
+	"sync"
+)
+
+// ParamSet is one combination of parameters tried by Sweep.
+type ParamSet struct {
+	Seed int // The seed the wave is initialized with
+
+	// Weights overriding Module.Weight for some input modules, the other
+	// modules keep theirs. Values <= 0 count as 1, like Module.Weight.
+	Weights map[*Module]float64
+}
+
+// SweepResult is the outcome of collapsing a wave with one ParamSet.
+type SweepResult struct {
+	Params  ParamSet
+	Success bool  // True if the wave collapsed without contradictions
+	Err     error // The collapse error, nil on success
+	Wave    *Wave // The collapsed copy of the wave, e.g. for SimilarityTo
+
+	// DiversityScore of the output.
+	Diversity float64
+
+	// Average fraction of slots that differ from the outputs of the other
+	// parameter sets (see CollapseNovel), 0 if there is only one.
+	Novelty float64
+}
+
+// Sweep collapses one copy of the wave with CollapseAuto per parameter set and
+// scores the outputs, to automate the search for good weights: e.g. run every
+// combination of a few seeds and weights, then compare the scores of the
+// results. The copies share the wave's input and settings and are collapsed by
+// a bounded pool of workers, one per CPU, without modifying the wave or its
+// modules. Results are returned in the order of the parameter sets.
+//
+// The shared settings include the hooks (IsPossibleFn, SelectionFn,
+// SelectSlotFn, DynamicWeightFn, OnAttempt, OnPropagate and the Logger), which
+// are therefore called from several goroutines at once and must be safe for
+// concurrent use.
+func (w *Wave) Sweep(params []ParamSet) []SweepResult {
+	results := make([]SweepResult, len(params))
+	jobs := make(chan int)
+
+	workers := runtime.NumCPU()
+	if workers > len(params) {
+		workers = len(params)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				p := params[i]
+				wave := w.spawn()
+				wave.weights = make(map[*Module]float64, len(p.Weights))
+				for m, wt := range p.Weights {
+					if wt <= 0 {
+						wt = 1
+					}
+					wave.weights[m] = wt
+				}
+				wave.Initialize(p.Seed)
+				err := wave.CollapseAuto()
+
+				results[i] = SweepResult{
+					Params:    p,
+					Success:   err == nil,
+					Err:       err,
+					Wave:      wave,
+					Diversity: wave.DiversityScore(),
+				}
+			}
+		}()
+	}
+
+	for i := range params {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(results) > 1 {
+		for i := range results {
+			sum := 0.0
+			for j := range results {
+				if i != j {
+					sum += results[i].Wave.difference(results[j].Wave)
+				}
+			}
+			results[i].Novelty = sum / float64(len(results)-1)
+		}
+	}
+
+	return results
+}
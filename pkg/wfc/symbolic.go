@@ -0,0 +1,42 @@
+package wfc
+
+// AdjacencyRule reports whether tile "b" may be placed next to tile "a" in the
+// given direction. Tiles are identified by their index.
+type AdjacencyRule func(a, b int, d Direction) bool
+
+// NewSymbolic creates a wave collapse function for tiles that are only
+// identified by an index from 0 to numTiles-1, without any images. The given
+// rule decides which tiles may be neighbors; a pairing is possible only if the
+// rule allows it from both sides.
+//
+// This is meant for headless generation where only the tile index map is
+// needed, see CollapseSymbolic. Modules have no image, so ExportImage and the
+// other image exports can't be used.
+func NewSymbolic(numTiles int, rule AdjacencyRule, width, height int) *Wave {
+	modules := make([]*Module, numTiles)
+	for i := range modules {
+		modules[i] = &Module{Index: i}
+	}
+
+	// All modules share the same constraints, the rule is applied with Forbid
+	for _, a := range modules {
+		for _, b := range modules {
+			for _, d := range Directions {
+				if !rule(a.Index, b.Index, d) {
+					Forbid(a, b, d)
+				}
+			}
+		}
+	}
+
+	return NewFromModules(modules, width, height)
+}
+
+// CollapseSymbolic collapses the wave with CollapseAuto and returns the grid of
+// tile indices (see ExportIndices) without rendering anything.
+//
+// The wave must be initialized before calling this.
+func (w *Wave) CollapseSymbolic() ([][]int, error) {
+	err := w.CollapseAuto()
+	return w.ExportIndices(), err
+}
@@ -0,0 +1,91 @@
+package wfc
+
+import "image"
+
+// SymmetryClass describes which rotations and reflections leave a tile
+// unchanged. The classes follow the letters commonly used for tile symmetry in
+// wave function collapse tilesets: a tile is named after the letter with the
+// same symmetry.
+type SymmetryClass int
+
+const (
+	// SymmetryNone: no rotation or reflection leaves the tile unchanged,
+	// like the letter F.
+	SymmetryNone SymmetryClass = iota
+
+	// SymmetryT: mirrored along the vertical or horizontal axis only.
+	SymmetryT
+
+	// SymmetryL: mirrored along one diagonal only.
+	SymmetryL
+
+	// SymmetryHalfTurn: unchanged by a half turn only, like the letter S.
+	SymmetryHalfTurn
+
+	// SymmetryI: unchanged by a half turn and mirrored along both axes.
+	SymmetryI
+
+	// SymmetryDiagonal: unchanged by a half turn and mirrored along both
+	// diagonals, like a backslash.
+	SymmetryDiagonal
+
+	// SymmetryQuarterTurn: unchanged by a quarter turn, but not by
+	// reflection, like a pinwheel.
+	SymmetryQuarterTurn
+
+	// SymmetryX: unchanged by every rotation and reflection.
+	SymmetryX
+)
+
+// Rotations returns the number of distinct rotations of a tile with this
+// symmetry: 1, 2 or 4.
+func (c SymmetryClass) Rotations() int {
+	switch c {
+	case SymmetryX, SymmetryQuarterTurn:
+		return 1
+	case SymmetryI, SymmetryDiagonal, SymmetryHalfTurn:
+		return 2
+	default:
+		return 4
+	}
+}
+
+// DetectSymmetry compares a tile to its own rotations and reflections and
+// returns its symmetry class. Pixels are compared exactly. Tiles that aren't
+// square can't be symmetric under quarter turns or diagonal reflection.
+func DetectSymmetry(img image.Image) SymmetryClass {
+	reflected := reflectImage(img)
+
+	quarter := imagesEqual(img, rotateImage(img, 1))
+	half := imagesEqual(img, rotateImage(img, 2))
+	axis := imagesEqual(img, reflected) || imagesEqual(img, rotateImage(reflected, 2))
+	diagonal := imagesEqual(img, rotateImage(reflected, 1)) || imagesEqual(img, rotateImage(reflected, 3))
+
+	switch {
+	case quarter && axis:
+		return SymmetryX
+	case quarter:
+		return SymmetryQuarterTurn
+	case half && axis:
+		return SymmetryI
+	case half && diagonal:
+		return SymmetryDiagonal
+	case half:
+		return SymmetryHalfTurn
+	case axis:
+		return SymmetryT
+	case diagonal:
+		return SymmetryL
+	default:
+		return SymmetryNone
+	}
+}
+
+// rotationPeriod returns the number of distinct rotations of a module's image,
+// or 4 if it has none.
+func rotationPeriod(m *Module) int {
+	if m.Image == nil {
+		return 4
+	}
+	return DetectSymmetry(m.Image).Rotations()
+}
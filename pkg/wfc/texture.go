@@ -0,0 +1,52 @@
+package wfc
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+var (
+	ErrNotSeamless = errors.New("output does not tile seamlessly")
+)
+
+// TileableTexture generates an image that tiles seamlessly when repeated. It
+// enables WrapX and WrapY (they stay enabled afterwards), initializes the wave
+// with the given seed and collapses it with CollapseAuto.
+//
+// The opposite edges of the result are checked against each other, and
+// ErrNotSeamless is returned along with the coordinates of the first pair of
+// tiles that don't fit.
+func (w *Wave) TileableTexture(seed int) (image.Image, error) {
+	w.WrapX = true
+	w.WrapY = true
+
+	w.Initialize(seed)
+	if err := w.CollapseAuto(); err != nil {
+		return w.ExportImage(), err
+	}
+
+	if err := w.checkSeamless(); err != nil {
+		return w.ExportImage(), err
+	}
+
+	return w.ExportImage(), nil
+}
+
+// checkSeamless checks that the tiles on opposite edges of the collapsed wave
+// may be placed next to each other.
+func (w *Wave) checkSeamless() error {
+	for y := 0; y < w.Height; y++ {
+		left, right := w.GetSlot(0, y), w.GetSlot(w.Width-1, y)
+		if !w.isPossible(left.Superposition[0], right, left, Right) {
+			return fmt.Errorf("tiles at %d,%d and %d,%d: %w", right.X, right.Y, left.X, left.Y, ErrNotSeamless)
+		}
+	}
+	for x := 0; x < w.Width; x++ {
+		top, bottom := w.GetSlot(x, 0), w.GetSlot(x, w.Height-1)
+		if !w.isPossible(top.Superposition[0], bottom, top, Down) {
+			return fmt.Errorf("tiles at %d,%d and %d,%d: %w", bottom.X, bottom.Y, top.X, top.Y, ErrNotSeamless)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package wfc
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Name of the optional metadata file read by LoadTileset.
+const TilesetFile = "tileset.json"
+
+// TilesetConfig is the content of a tileset.json file. Tiles are keyed by
+// their file name in the tileset directory.
+//
+//	{
+//	  "tiles": {
+//	    "road.png": {
+//	      "weight": 2,
+//	      "rotations": [1, 2, 3],
+//	      "sockets": {"up": "road", "down": "road", "left": "grass", "right": "grass"},
+//	      "tags": ["road"]
+//	    }
+//	  }
+//	}
+type TilesetConfig struct {
+	Tiles map[string]TileConfig `json:"tiles"`
+}
+
+// TileConfig holds the metadata of a single tile in a tileset.json file. All
+// fields are optional.
+type TileConfig struct {
+	// Relative likelihood of the tile, defaults to 1.
+	Weight float64 `json:"weight"`
+
+	// Clockwise quarter turns (1 to 3) for which a rotated variant is added
+	// to the input. Defaults to none.
+	Rotations []int `json:"rotations"`
+
+	// Named sockets per direction ("up", "down", "left", "right"). Edges
+	// with the same socket name can be neighbors. Edges without a socket are
+	// derived from the tile image with DefaultConstraintFunc. Sockets are
+	// symmetric: they are not reversed when a tile is rotated.
+	Sockets map[string]string `json:"sockets"`
+
+	// Free-form labels, copied to Module.Tags.
+	Tags []string `json:"tags"`
+}
+
+// LoadTileset loads all images in a directory and creates a wave collapse
+// function of the given size from them.
+//
+// If the directory contains a tileset.json (see TilesetConfig), the weight,
+// rotations, sockets and tags of each tile are taken from it. Tiles that are
+// missing from the file, or the whole file, fall back to the defaults: a
+// weight of 1, no rotated variants and constraints derived from the image.
+func LoadTileset(dir string, width, height int) (*Wave, error) {
+	var config TilesetConfig
+	raw, err := os.ReadFile(filepath.Join(dir, TilesetFile))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, fmt.Errorf("%s: %w", TilesetFile, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	files, err := readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]*Module, 0)
+	found := make(map[string]bool)
+	for _, file := range files {
+		if !isImageFile(file) {
+			continue
+		}
+		img, err := LoadImage(filepath.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+		found[file] = true
+
+		tile := config.Tiles[file]
+		module := &Module{
+			Index:  len(modules),
+			Image:  img,
+			Weight: tile.Weight,
+			Tags:   tile.Tags,
+		}
+
+		sockets := [4]bool{}
+		for name, socket := range tile.Sockets {
+			d, err := directionFromName(name)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", TilesetFile, file, err)
+			}
+			module.Adjacencies[d] = socketConstraint(socket)
+			sockets[d] = true
+		}
+		for _, d := range Directions {
+			if !sockets[d] {
+				module.Adjacencies[d] = DefaultConstraintFunc(img, d)
+			}
+		}
+		modules = append(modules, module)
+
+		for _, q := range tile.Rotations {
+			if q%4 == 0 {
+				continue
+			}
+			rotated := RotateModule(module, q)
+			rotated.Index = len(modules)
+
+			// Image-derived edges are recomputed from the rotated image,
+			// sockets are carried over by RotateModule
+			moved := sockets
+			for i := 0; i < ((q%4)+4)%4; i++ {
+				s := moved
+				moved[Up], moved[Right], moved[Down], moved[Left] = s[Left], s[Up], s[Right], s[Down]
+			}
+			for _, d := range Directions {
+				if !moved[d] {
+					rotated.Adjacencies[d] = DefaultConstraintFunc(rotated.Image, d)
+				}
+			}
+			modules = append(modules, rotated)
+		}
+	}
+
+	for file := range config.Tiles {
+		if !found[file] {
+			return nil, fmt.Errorf("%s: tile %q not found in %s", TilesetFile, file, dir)
+		}
+	}
+
+	return NewFromModules(modules, width, height), nil
+}
+
+// directionFromName parses a direction name as used in tileset.json.
+func directionFromName(name string) (Direction, error) {
+	switch name {
+	case "up":
+		return Up, nil
+	case "down":
+		return Down, nil
+	case "left":
+		return Left, nil
+	case "right":
+		return Right, nil
+	}
+	return Up, fmt.Errorf("unknown direction %q", name)
+}
+
+// socketConstraint returns the adjacency constraint id for a named socket.
+func socketConstraint(socket string) ConstraintId {
+	sum := sha256.Sum256([]byte("socket:" + socket))
+	res := fmt.Sprintf("%x", sum)[:8]
+
+	var id ConstraintId
+	copy(id[:], res)
+	return id
+}
@@ -0,0 +1,83 @@
+package wfc
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+var (
+	ErrTileSizeMismatch = errors.New("input tiles have different sizes")
+)
+
+// Validate checks that all input tiles share the same size and that every
+// slot of the wave has at least one possible module.
+//
+// Mixing tile sizes is not supported: every slot is drawn with the size of the
+// first input tile, so mismatched tiles would produce garbled output. Such
+// tilesets are reported with ErrTileSizeMismatch.
+//
+// If the wave has been initialized, the current possibility space is checked.
+// Otherwise, the slots that Initialize would create are checked instead, which
+// catches placement rules (see BorderOnly and InteriorOnly) that leave part of
+// the grid without any options.
+func (w *Wave) Validate() error {
+	if err := w.validateTileSizes(); err != nil {
+		return err
+	}
+
+	for x := 0; x < w.Width; x++ {
+		for y := 0; y < w.Height; y++ {
+			var count int
+			if w.PossibilitySpace != nil {
+				count = len(w.GetSlot(x, y).Superposition)
+			} else {
+				count = len(w.allowedAt(x, y))
+			}
+
+			if count == 0 {
+				return fmt.Errorf("slot %d,%d: %w", x, y, ErrNoSolution)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateTileSizes checks that every input module image has the same size.
+// Modules without an image are ignored.
+func (w *Wave) validateTileSizes() error {
+	var size image.Point
+	for i, m := range w.Input {
+		if m.Image == nil {
+			continue
+		}
+		s := m.Image.Bounds().Size()
+		if size == (image.Point{}) {
+			size = s
+			continue
+		}
+		if s != size {
+			return fmt.Errorf("tile %d is %dx%d, expected %dx%d: %w",
+				i, s.X, s.Y, size.X, size.Y, ErrTileSizeMismatch)
+		}
+	}
+	return nil
+}
+
+// TilesWithNoNeighbors returns the input modules that can't be placed next to
+// any input module in at least one direction. Such a module causes a
+// contradiction wherever it is placed with a neighbor in that direction, which
+// is the most common mistake when authoring a tileset.
+func (w *Wave) TilesWithNoNeighbors() []*Module {
+	res := make([]*Module, 0)
+	for i, edges := range w.AdjacencyGraph() {
+		for _, d := range Directions {
+			if len(edges[d]) == 0 {
+				res = append(res, w.Input[i])
+				break
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,50 @@
+package wfc
+
+import (
+	"image"
+	"image/color"
+)
+
+// Color of the diagonals that separate the edges of Wang tiles.
+var wangDiagonalColor color.Color = color.RGBA{32, 32, 32, 255}
+
+// GenerateWangTiles generates the complete set of Wang tiles for the given edge
+// colors: one tile for every combination of colors on its four edges, so
+// len(colors)^4 tiles in total. Every tile is split along its diagonals into
+// four triangles, each filled with the color of its edge. The digits of the
+// tile index in base len(colors) are the colors of the top, right, bottom and
+// left edge, starting with the least significant one.
+//
+// The pixels along an edge only depend on the color of that edge, the corners
+// being drawn in the color of the diagonals. So the tiles can be fed straight
+// into New, or NewWithCustomConstraints with NewFullEdgeConstraintFunc, to
+// get a tileset where neighbors match if their shared edge has the same color.
+func GenerateWangTiles(colors []color.Color, tileSize int) []image.Image {
+	n := len(colors)
+	tiles := make([]image.Image, n*n*n*n)
+	for i := range tiles {
+		top, right, bottom, left := colors[i%n], colors[i/n%n], colors[i/(n*n)%n], colors[i/(n*n*n)]
+
+		img := image.NewRGBA(image.Rect(0, 0, tileSize, tileSize))
+		for x := 0; x < tileSize; x++ {
+			for y := 0; y < tileSize; y++ {
+				// Distance to each edge, the closest edge colors the pixel
+				dt, db, dl, dr := y, tileSize-1-y, x, tileSize-1-x
+				c := wangDiagonalColor
+				switch {
+				case dt < db && dt < dl && dt < dr:
+					c = top
+				case db < dt && db < dl && db < dr:
+					c = bottom
+				case dl < dt && dl < db && dl < dr:
+					c = left
+				case dr < dt && dr < db && dr < dl:
+					c = right
+				}
+				img.Set(x, y, c)
+			}
+		}
+		tiles[i] = img
+	}
+	return tiles
+}
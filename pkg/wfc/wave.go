@@ -1,18 +1,33 @@
 package wfc
 
 import (
+	crand "crypto/rand"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
+	"iter"
+	"log/slog"
+	"math"
 	"math/rand"
+	"sync"
+	"time"
 )
 
 var (
-	ErrNoSolution = errors.New("no possible modules for slot")
+	ErrNoSolution   = errors.New("no possible modules for slot")
+	ErrNotCollapsed = errors.New("wave is not fully collapsed")
+
+	ErrNotInitialized = errors.New("wave is not initialized, call Initialize first")
+	ErrEmptyInput     = errors.New("wave has no input tiles")
+	ErrExportTooLarge = errors.New("exported image would be too large")
 )
 
+// Default for Wave.MaxExportPixels, 256 megapixels or 1 GiB of RGBA data.
+const DefaultMaxExportPixels = 1 << 28
+
 // Wave holds the state of a wave collapse function as described by Oskar
 // Stalberg.
 //
@@ -32,15 +47,181 @@ type Wave struct {
 	Input            []*Module // Input tiles (possible tiles at each slot)
 	PossibilitySpace []*Slot   // The 2D grid of slots
 
-	History []*Slot // Slots that have been visited during the current/last collapse iteration
+	// Slots on the current propagation path. This is working state of
+	// Recurse: it is mutated while propagating and cleared after every step,
+	// so don't hold on to it. Use DecisionLog for the order of decisions.
+	History []*Slot
 
 	// Override this if you'd like custom logic when checking if a state is
 	// possible from a direction. This is useful if you'd like to slow down the
-	// collapse or add probabilities.
+	// collapse or add probabilities. When nil, the default, modules are
+	// checked like DefaultIsPossibleFunc does and the results are memoized
+	// (see cachedPossibleModules), which a custom function opts out of.
 	IsPossibleFn IsPossibleFunc
 
 	// Function used to calculate image constraints
 	ConstraintFn ConstraintFunc
+
+	// Optional structured logger. When set, key events of the collapse
+	// (initialization, collapse decisions, propagation and contradictions)
+	// are logged at debug level. When nil, nothing is logged.
+	Logger *slog.Logger
+
+	// Optional function choosing the module a slot collapses into. When nil,
+	// or when it returns nil or a module that isn't in the slot's
+	// superposition, a random module is chosen instead.
+	SelectionFn SelectionFunc
+
+	// Optional function adjusting the weight of a module while collapsing,
+	// see DynamicWeightFunc.
+	DynamicWeightFn DynamicWeightFunc
+
+	// Optional hook called by Collapse after every attempt with the attempt
+	// number (starting at 0), the exported image of the wave at that point
+	// and the error of the attempt, if any. The image is only rendered when
+	// the hook is set.
+	OnAttempt func(attempt int, img image.Image, err error)
+
+	// Optional hook called during propagation whenever the constraints of
+	// slot "from" remove modules from its neighbor "to" in direction d, to
+	// follow how constraints flow through the grid. It is not called with
+	// ParallelPropagation. The hook runs while Step holds the lock that
+	// Snapshot waits for, so calling Snapshot from it deadlocks; read the
+	// possibility space directly instead, as ExportFrames does.
+	OnPropagate func(from, to *Slot, d Direction)
+
+	// Wrap the grid around horizontally and/or vertically, so that slots on
+	// opposite edges are neighbors. Useful for seamless textures.
+	WrapX, WrapY bool
+
+	// Soft preference for modules that match already collapsed neighbors.
+	// When collapsing a slot, the weight of a module is multiplied by
+	// 1 + CohesionBias for every neighbor collapsed into the same module,
+	// which favors large contiguous regions over noise. 0 disables it.
+	CohesionBias float64
+
+	// Soft penalty against runs of the same module. When collapsing a slot,
+	// the weight of the module chosen by the previous decision is multiplied
+	// by 1 - RepetitionPenalty, so 1 rules it out whenever there is another
+	// option. 0 disables it.
+	RepetitionPenalty float64
+
+	// Per-direction preference for continuing a module, for directional
+	// textures like waterfalls. When collapsing a slot, the weight of a
+	// module is multiplied by DirectionalWeight[d] for every neighbor in
+	// direction d that is collapsed into the same module. E.g. {Up: 4} makes
+	// modules four times as likely to continue the module above them, which
+	// grows vertical streaks. Directions that aren't set count as 1.
+	DirectionalWeight map[Direction]float64
+
+	// Experimental: propagate constraints concurrently, see
+	// propagateParallel. IsPossibleFn must be safe for concurrent use when
+	// this is enabled.
+	ParallelPropagation bool
+
+	// Shuffle the modules of every slot with the wave's random source when
+	// it is initialized. Superpositions otherwise keep the order of the
+	// input, which leaks into the output: with the same seed, reordering
+	// the input tiles changes the result, and strategies that favor the
+	// first matching module favor the first tiles. Shuffling is reproducible
+	// for a seed, but gives different outputs than an unshuffled wave.
+	ShuffleModules bool
+
+	// Strategy used to pick the next slot to collapse, defaults to random.
+	Selection SelectionStrategy
+
+	// Randomness of SelectionStrategyLowestEntropy. Every slot gets a random
+	// noise value in [0, 1), scaled by EntropyNoise and added to its entropy
+	// before the lowest one is picked. The default 0 strictly picks a slot
+	// with the lowest entropy, using the noise only to break ties. Above 1, a
+	// slot may be picked over slots with fewer than EntropyNoise modules less,
+	// so larger values make the selection increasingly random; around 2 to 4
+	// is a good start. Any value above 0 overrides the importance of slots
+	// (see SetImportance) and the TieBreak policy.
+	EntropyNoise float64
+
+	// How SelectionStrategyLowestEntropy breaks ties between slots of equal
+	// entropy, defaults to random.
+	TieBreak TieBreakPolicy
+
+	// Optional function choosing the slot that is collapsed next, to
+	// experiment with custom heuristics. When nil, or when it returns nil or
+	// a slot that is already collapsed or belongs to another wave, Selection
+	// picks the slot instead.
+	SelectSlotFn SlotSelectionFunc
+
+	// Order in which neighbors are visited during propagation, defaults to
+	// the fixed order of Directions.
+	Propagation PropagationOrder
+
+	// Make ExportFrames save a frame for every propagation (see OnPropagate)
+	// instead of every step, with an arrow on the slot the constraint flows
+	// from, pointing in the direction it flows.
+	FrameArrows bool
+
+	// Upper bound of the number of pixels of the images rendered by
+	// ExportImage and the exports built on it, to protect servers from huge
+	// grid or tile sizes. 0 uses DefaultMaxExportPixels, a negative value
+	// only guards against overflows. See CheckExportSize.
+	MaxExportPixels int
+
+	// Thickness in pixels of the grid lines drawn between tiles by
+	// ExportImage, 0 disables them. The lines are centered on the tile
+	// boundaries and cover the edge pixels of the tiles on either side, so
+	// the image keeps its size. GridColor defaults to black.
+	GridLines int
+	GridColor color.Color
+
+	// How ExportImage renders slots collapsed into a fully transparent tile,
+	// which otherwise look the same as uncollapsed slots.
+	TransparentTiles TransparentTileMode
+
+	// Optional function returning the image ExportImage draws for a slot in
+	// a contradiction state, e.g. a dedicated error tile. When nil, or when
+	// it returns nil, the slot is filled with red.
+	ContradictionRender func(s *Slot) image.Image
+
+	// Collect performance counters of the propagation, see Stats. Disabled
+	// by default to avoid the overhead.
+	CollectStats bool
+
+	// Record the entropy of the grid after every Step, see EntropyHistory.
+	// Disabled by default to avoid the overhead.
+	RecordEntropy bool
+
+	rng       *rand.Rand     // Random source seeded by Initialize
+	seed      int            // Seed passed to Initialize, see Seed
+	steps     int            // Number of collapse decisions made so far
+	rules     uint64         // Version of the wave's rules, see rulesChanged
+	decisions []image.Point  // Slots collapsed by a decision, in order
+	entropy   *entropyHeap   // Cached slot entropies for lowest-entropy selection
+	possible  *possibleCache // Memoized results of GetPossibleModules
+	initial   [][]*Module    // Superposition of every slot after initialization
+
+	importance [][]float64 // Tie-breaker for lowest-entropy selection, see SetImportance
+
+	borderOnly   map[*Module]bool // Modules only allowed on the outer ring
+	interiorOnly map[*Module]bool // Modules not allowed on the outer ring
+	patterns     [][][]*Module    // Forbidden patterns, see ForbidPattern
+	patternQueue []*Slot          // Collapsed slots to check against the patterns
+
+	multiTileParts map[*Module]multiTilePart // Modules of multi-tile structures, see AddMultiTile
+	tagAffinity    map[[2]string]float64     // Soft rules between tags, see TagAffinity
+	weights        map[*Module]float64       // Weights overriding Module.Weight, see Sweep
+	pairRules      map[rulePair]bool         // Rules overriding those of the modules, see CanNeighbor
+
+	placed      map[*Slot]*Module // Module of every collapsed slot while DynamicWeightFn is set
+	placedCount map[*Module]int   // Number of collapsed slots per module, see placed
+
+	locked      map[image.Point]*Module // Slots kept by Regenerate, see LockCollapsed
+	checkpoints map[string]*checkpoint  // Saved states, see Checkpoint
+
+	start       *image.Point // First slot to collapse, see SetStartSlot
+	startModule *Module      // Module the first slot collapses into
+
+	stats          Stats         // Counters collected while CollectStats is set
+	entropyHistory []EntropyStat // Entropy after every Step while RecordEntropy is set
+	mu             *sync.RWMutex // Guards the possibility space between Step and Snapshot, see reset
 }
 
 // New creates a new wave collapse function with the given width and height and
@@ -63,13 +244,7 @@ func New(tiles []image.Image, width, height int) *Wave {
 // adjacency constraint calculation function. Use this if you'd like custom
 // logic for specifying constraints.
 func NewWithCustomConstraints(tiles []image.Image, width, height int, fn ConstraintFunc) *Wave {
-	wave := &Wave{
-		Width:        width,
-		Height:       height,
-		Input:        make([]*Module, len(tiles)),
-		ConstraintFn: fn,
-		IsPossibleFn: DefaultIsPossibleFunc,
-	}
+	modules := make([]*Module, len(tiles))
 
 	// Automatically generate adjacency constraints for each input tile.
 	for i, tile := range tiles {
@@ -77,32 +252,144 @@ func NewWithCustomConstraints(tiles []image.Image, width, height int, fn Constra
 		for _, d := range Directions {
 			module.Adjacencies[d] = fn(tile, d)
 		}
-		wave.Input[i] = &module
+		modules[i] = &module
 	}
 
+	wave := NewFromModules(modules, width, height)
+	wave.ConstraintFn = fn
 	return wave
 }
 
+// NewFromModules creates a new wave collapse function from modules that
+// already have their adjacency constraints populated, e.g. modules analyzed
+// once and reused across many generations. No constraints are derived from the
+// module images.
+//
+// The modules are used as-is and may be shared between waves. ConstraintFn is
+// left unset, so the adjacencies of the modules are authoritative. Helpers
+// that need to hash images, such as InitializePrepopulated, fall back to
+// DefaultConstraintFunc.
+func NewFromModules(modules []*Module, width, height int) *Wave {
+	return &Wave{
+		Width:  width,
+		Height: height,
+		Input:  modules,
+		mu:     &sync.RWMutex{},
+	}
+}
+
 // Initialize sets up the wave collapse function so that every slot is in a
 // superposition of all input tiles/modules.
 //
-// Each module is equally likely to be at each slot.
+// Each module is equally likely to be at each slot. The seed initializes a
+// random source owned by the wave, so several waves may be collapsed
+// concurrently and each one stays reproducible. Modules marked with
+// BorderOnly or InteriorOnly are left out of the slots they may not occupy.
 func (w *Wave) Initialize(seed int) {
-	rand.Seed(int64(seed)) // TODO: move off rand... this isn't thread safe; we can do better :)
+	w.rng = rand.New(rand.NewSource(int64(seed)))
+	w.seed = seed
 
-	w.PossibilitySpace = make([]*Slot, w.Width*w.Height)
+	w.reset()
 	for x := 0; x < w.Width; x++ {
 		for y := 0; y < w.Height; y++ {
 			slot := Slot{
 				X: x, Y: y,
-				Superposition: make([]*Module, len(w.Input)),
+				Superposition: w.initialSuperposition(x, y),
+				CollapsedAt:   -1,
 			}
-			copy(slot.Superposition, w.Input)
+			w.markCollapsed(&slot)
 			w.PossibilitySpace[x+y*w.Width] = &slot
 		}
 	}
 
-	w.DumpPossibilitySpace()
+	w.recordInitial()
+	w.logInitialized()
+}
+
+// InitializeRandom sets up the wave like Initialize, with a seed read from
+// crypto/rand, so every call produces a different output, e.g. for a public
+// generator. Use Seed to record the seed and reproduce the output later with
+// Initialize. Falls back to the current time if crypto/rand fails.
+func (w *Wave) InitializeRandom() {
+	var seed int64
+	var buf [8]byte
+	if _, err := crand.Read(buf[:]); err == nil {
+		seed = int64(binary.BigEndian.Uint64(buf[:]) >> 1)
+	} else {
+		seed = time.Now().UnixNano()
+	}
+	w.Initialize(int(seed))
+}
+
+// Seed returns the seed the wave was last initialized with.
+func (w *Wave) Seed() int {
+	return w.seed
+}
+
+// reset clears all collapse state and allocates an empty possibility space.
+// It also creates the lock shared by Step and Snapshot if the wave was built
+// without a constructor, e.g. as a struct literal.
+func (w *Wave) reset() {
+	if w.mu == nil {
+		w.mu = &sync.RWMutex{}
+	}
+	w.PossibilitySpace = make([]*Slot, w.Width*w.Height)
+	w.History = make([]*Slot, 0)
+	w.entropy = nil
+	w.possible = nil
+	w.initial = nil
+	w.placed = nil
+	w.placedCount = nil
+	w.steps = 0
+	w.decisions = nil
+	w.patternQueue = nil
+	w.checkpoints = nil
+	w.stats = Stats{}
+	w.entropyHistory = nil
+}
+
+// recordInitial saves the superposition of every slot as its initial state,
+// which CollapseResilient resets slots to. Initializers that restrict slots
+// beyond Initialize call it again once the restrictions are in place.
+func (w *Wave) recordInitial() {
+	w.initial = make([][]*Module, len(w.PossibilitySpace))
+	for i, s := range w.PossibilitySpace {
+		w.initial[i] = make([]*Module, len(s.Superposition))
+		copy(w.initial[i], s.Superposition)
+	}
+}
+
+// initialSuperposition returns the modules of a slot before any collapse takes
+// place, shuffled if ShuffleModules is set.
+func (w *Wave) initialSuperposition(x, y int) []*Module {
+	modules := w.allowedAt(x, y)
+	if w.ShuffleModules {
+		w.rng.Shuffle(len(modules), func(i, j int) {
+			modules[i], modules[j] = modules[j], modules[i]
+		})
+	}
+	return modules
+}
+
+// Release frees the possibility space and the caches built while collapsing, so
+// a long-lived process can bound its memory without waiting for the garbage
+// collector to find the wave unreachable. Export the output before calling it.
+//
+// The input modules and settings are kept, so the wave can be reused after
+// calling Initialize (or one of its variants) again. Until then, methods that
+// need the possibility space return ErrNotInitialized, GetSlot and GetNeighbor
+// return nil, and the exports render an empty grid. Settings and input can
+// still be changed freely.
+func (w *Wave) Release() {
+	if w.mu != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+	}
+
+	w.reset()
+	w.PossibilitySpace = nil
+	w.History = nil
+	w.rng = nil
 }
 
 // Little helper to compute a "checksum"  of an image. We just compute
@@ -116,8 +403,13 @@ func (w *Wave) Initialize(seed int) {
 func (w *Wave) ImageChecksum(img image.Image) string {
 	sums := make([]byte, 4*8)
 
+	fn := w.ConstraintFn
+	if fn == nil {
+		fn = DefaultConstraintFunc
+	}
+
 	for _, d := range Directions {
-		sum := w.ConstraintFn(img, d)
+		sum := fn(img, d)
 		for _, b := range sum {
 			sums = append(sums, b)
 		}
@@ -131,13 +423,14 @@ func (w *Wave) ImageChecksum(img image.Image) string {
 // the superposition  of all input tiles/modules,  but non-transparent
 // ones will lead to a readily constrained slot for that position.
 func (w *Wave) InitializePrepopulated(mapimage image.Image, seed int) error {
-	rand.Seed(int64(seed))
+	w.rng = rand.New(rand.NewSource(int64(seed)))
+	w.seed = seed
 
 	// needed to extract subimages from the map image
 	tilesize := mapimage.Bounds().Dx() / w.Width
 
 	// init
-	w.PossibilitySpace = make([]*Slot, w.Width*w.Height)
+	w.reset()
 
 	// pre-calculate input image checksums
 	checksums := make([]string, len(w.Input))
@@ -156,12 +449,12 @@ func (w *Wave) InitializePrepopulated(mapimage image.Image, seed int) error {
 			// default slot, has all modules
 			slot := Slot{
 				X: x, Y: y,
-				Superposition: make([]*Module, len(w.Input)),
+				CollapsedAt: -1,
 			}
 
 			if tileIsTransparent(tile) {
 				// behave as the standard Initialize()
-				copy(slot.Superposition, w.Input)
+				slot.Superposition = w.initialSuperposition(x, y)
 			} else {
 				// found a pre-populated image in the tileset
 				// first, find input tile index matching current tile
@@ -183,15 +476,54 @@ func (w *Wave) InitializePrepopulated(mapimage image.Image, seed int) error {
 				slot.Superposition = []*Module{module}
 			}
 
+			w.markCollapsed(&slot)
 			w.PossibilitySpace[x+y*w.Width] = &slot
 		}
 	}
 
-	w.DumpPossibilitySpace()
+	w.recordInitial()
+	w.logInitialized()
 
 	return nil
 }
 
+// logInitialized logs the size of a freshly initialized possibility space.
+func (w *Wave) logInitialized() {
+	if w.Logger == nil {
+		return
+	}
+	w.Logger.Debug("wave initialized",
+		"width", w.Width, "height", w.Height, "modules", len(w.Input))
+}
+
+// logCollapse logs the decision made for a freshly collapsed slot.
+func (w *Wave) logCollapse(s *Slot) {
+	if w.Logger == nil {
+		return
+	}
+	w.Logger.Debug("collapse", "x", s.X, "y", s.Y, "module", s.Superposition[0].Index)
+}
+
+// logPropagate logs the modules removed from a slot during propagation. Extra
+// key-value pairs are appended to the record.
+func (w *Wave) logPropagate(s *Slot, removed, remaining int, args ...interface{}) {
+	if w.Logger == nil {
+		return
+	}
+	args = append([]interface{}{"x", s.X, "y", s.Y}, args...)
+	w.Logger.Debug("propagate", append(args, "removed", removed, "remaining", remaining)...)
+}
+
+// logContradiction logs a slot that has no possible module left.
+func (w *Wave) logContradiction(s *Slot) {
+	if w.Logger == nil {
+		return
+	}
+	w.Logger.Debug("contradiction", "x", s.X, "y", s.Y)
+}
+
+// DumpPossibilitySpace prints every slot and the constraints of the modules in
+// its superposition to stdout. Useful when debugging a tileset.
 func (w *Wave) DumpPossibilitySpace() {
 	for index, slot := range w.PossibilitySpace {
 		fmt.Printf("slot %d, modules: %d:\n", index, len(slot.Superposition))
@@ -213,22 +545,260 @@ func (w *Wave) DumpPossibilitySpace() {
 // function can return an error if a contradiction is found. You can still
 // export the image of a failed collapse to see which of your tiles is causing
 // issues for you.
+//
+// Returns ErrNotInitialized if the wave hasn't been set up with Initialize (or
+// one of its variants) yet. Set OnAttempt to capture the state of the wave
+// after each attempt.
 func (w *Wave) Collapse(attempts int) error {
 
 	for i := 0; i < attempts; i++ {
-		err := w.Recurse()
+		done, err := w.Step()
+		if w.OnAttempt != nil && !errors.Is(err, ErrNotInitialized) {
+			w.OnAttempt(i, w.ExportImage(), err)
+		}
 		if err != nil {
 			return err
 		}
-		w.History = make([]*Slot, 0)
+		if done {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Step performs a single iteration of the collapse: one slot is collapsed into
+// a single module and the change is propagated to its neighbors. It returns
+// true once every slot in the wave has been collapsed.
+//
+// Use this instead of Collapse if you'd like to drive the algorithm yourself,
+// e.g. to animate it or to spread the work across several frames. Snapshot
+// may be called from other goroutines while Step runs.
+func (w *Wave) Step() (bool, error) {
+	if err := w.checkInitialized(); err != nil {
+		return false, err
+	}
+	if w.mu != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+	}
+	if w.IsCollapsed() {
+		return true, nil
+	}
+
+	start := w.startStats()
+	var err error
+	if w.ParallelPropagation {
+		err = w.stepParallel()
+	} else {
+		err = w.Recurse()
+	}
+	w.stopStats(start)
+	w.History = make([]*Slot, 0)
+	if err == nil {
+		err = w.enforcePatterns()
 	}
+	w.recordEntropy()
+	if err != nil {
+		return false, err
+	}
+
+	return w.IsCollapsed(), nil
+}
 
+// CollapseSteps performs up to n steps of the collapse (see Step) and returns
+// true once every slot in the wave has been collapsed. The wave keeps its
+// state between calls, so a game loop can spread the collapse across frames
+// by calling it with a small budget every frame until it returns true.
+func (w *Wave) CollapseSteps(n int) (bool, error) {
+	if err := w.checkInitialized(); err != nil {
+		return false, err
+	}
+
+	done := w.IsCollapsed()
+	for i := 0; i < n && !done; i++ {
+		var err error
+		done, err = w.Step()
+		if err != nil {
+			return false, err
+		}
+	}
+	return done, nil
+}
+
+// checkInitialized returns ErrNotInitialized if Initialize hasn't been called
+// yet.
+func (w *Wave) checkInitialized() error {
+	if w.PossibilitySpace == nil || w.rng == nil {
+		return ErrNotInitialized
+	}
 	return nil
 }
 
+// collapseAll steps the wave until every slot is collapsed or a contradiction
+// is found.
+func (w *Wave) collapseAll() error {
+	for {
+		done, err := w.Step()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// propagate removes impossible modules from the neighbors of a slot whose
+// superposition has been constrained from the outside, e.g. when pinning it.
+func (w *Wave) propagate(s *Slot) error {
+	start := w.startStats()
+	var err error
+	if w.ParallelPropagation {
+		err = w.propagateParallel(s)
+	} else {
+		w.History = []*Slot{s}
+		err = w.Recurse()
+		w.History = make([]*Slot, 0)
+	}
+	w.stopStats(start)
+	if err != nil {
+		return err
+	}
+	return w.enforcePatterns()
+}
+
+// CollapseIter collapses the wave one Step at a time, calling yield with the
+// wave after each step. It stops once the wave is collapsed or yield returns
+// false, and returns the error of a failed step, e.g. ErrNoSolution on a
+// contradiction:
+//
+//	err := wave.CollapseIter(func(state *Wave) bool {
+//		render(state)
+//		return true
+//	})
+//
+// Because of the error result, CollapseIter can't be ranged over directly. Use
+// All for range-over-func.
+func (w *Wave) CollapseIter(yield func(state *Wave) bool) error {
+	for {
+		done, err := w.Step()
+		if err != nil {
+			return err
+		}
+		if !yield(w) || done {
+			return nil
+		}
+	}
+}
+
+// All returns an iterator over the collapse steps of the wave, like
+// CollapseIter, for use with range-over-func. It yields the wave and the error
+// of the step after each one, and stops after a failed step:
+//
+//	for state, err := range wave.All() {
+//		if err != nil {
+//			break
+//		}
+//		render(state)
+//	}
+func (w *Wave) All() iter.Seq2[*Wave, error] {
+	return func(yield func(*Wave, error) bool) {
+		for {
+			done, err := w.Step()
+			if !yield(w, err) || err != nil || done {
+				return
+			}
+		}
+	}
+}
+
 // CollapseRandomSlot takes a random slot and collapses it into a single module.
 // If the slot is already collapsed, it will pick another slot and try again.
 func (w *Wave) CollapseRandomSlot() *Slot {
+	slot := w.randomSlot()
+	if slot == nil {
+		return nil
+	}
+
+	w.decide(slot)
+
+	return slot
+}
+
+// decide collapses the given slot into a module and records it as a
+// new collapse step.
+func (w *Wave) decide(s *Slot) {
+	w.steps++
+	if m := w.selectModule(s); m != nil {
+		s.Superposition = []*Module{m}
+	} else {
+		w.collapseWeighted(s)
+	}
+	s.CollapsedAt = w.steps
+	w.decisions = append(w.decisions, image.Pt(s.X, s.Y))
+	w.queuePatterns(s)
+	w.recordPlaced(s)
+}
+
+// decideModule collapses the given slot into the given module and records it
+// as a new collapse step, like decide.
+func (w *Wave) decideModule(s *Slot, m *Module) {
+	w.steps++
+	s.Superposition = []*Module{m}
+	s.CollapsedAt = w.steps
+	w.decisions = append(w.decisions, image.Pt(s.X, s.Y))
+	w.queuePatterns(s)
+	w.recordPlaced(s)
+}
+
+// DecisionLog returns the coordinates of the slots that were collapsed by a
+// decision, in the order the decisions were made. Slots collapsed as a side
+// effect of propagation are not included, and decisions undone by
+// backtracking are dropped.
+//
+// The log is cleared by Initialize. The returned slice is a copy owned by the
+// caller.
+func (w *Wave) DecisionLog() []image.Point {
+	log := make([]image.Point, len(w.decisions))
+	copy(log, w.decisions)
+	return log
+}
+
+// selectModule asks the selection function for the module to collapse a slot
+// into, unless it is the start slot (see SetStartSlot). Returns nil if there
+// is no selection function or its choice is not possible.
+func (w *Wave) selectModule(s *Slot) *Module {
+	if w.startModule != nil && len(w.decisions) == 0 && w.start.Eq(image.Pt(s.X, s.Y)) {
+		if containsModule(s.Superposition, w.startModule) {
+			return w.startModule
+		}
+	}
+	if w.SelectionFn == nil {
+		return nil
+	}
+	m := w.SelectionFn(s)
+	for _, o := range s.Superposition {
+		if o == m {
+			return m
+		}
+	}
+	return nil
+}
+
+// markCollapsed records the current collapse step on a slot that just became
+// collapsed.
+func (w *Wave) markCollapsed(s *Slot) {
+	if len(s.Superposition) == 1 && s.CollapsedAt < 0 {
+		s.CollapsedAt = w.steps
+		w.queuePatterns(s)
+		w.recordPlaced(s)
+	}
+}
+
+// randomSlot picks a random slot that is not collapsed, or nil if all slots are
+// already collapsed.
+func (w *Wave) randomSlot() *Slot {
 	num_collapsed := 0
 	for _, s := range w.PossibilitySpace {
 		entropy := len(s.Superposition)
@@ -244,32 +814,38 @@ func (w *Wave) CollapseRandomSlot() *Slot {
 
 	// Pick a random slot that is not collapsed.
 	for {
-		slot := w.PossibilitySpace[rand.Intn(len(w.PossibilitySpace))]
+		slot := w.PossibilitySpace[w.rng.Intn(len(w.PossibilitySpace))]
 
 		if len(slot.Superposition) <= 1 {
 			continue
 		}
 
-		slot.Collapse()
-
 		return slot
 	}
 }
 
 // Recurse collapses the wave collapse function recursively.
 func (w *Wave) Recurse() error {
+	if err := w.checkInitialized(); err != nil {
+		return err
+	}
 	if w.IsCollapsed() {
 		return nil
 	}
 
 	// Check if we need to pick a starting point
 	if len(w.History) == 0 {
-		slot := w.CollapseRandomSlot()
+		slot := w.collapseNextSlot()
+		if slot == nil {
+			return nil
+		}
+		w.logCollapse(slot)
 		w.History = append(w.History, slot)
 	}
 
+	w.recordDepth(len(w.History))
 	previous := w.History[len(w.History)-1]
-	for _, d := range Directions {
+	for _, d := range w.propagationDirections(previous) {
 		if !w.HasNeighbor(previous, d) {
 			continue
 		}
@@ -286,11 +862,18 @@ func (w *Wave) Recurse() error {
 		} else {
 			// New superposition detected, we need to go deeper and remove
 			// impossible modules from the neighbor tiles
+			w.logPropagate(next, len(next.Superposition)-len(s), len(s), "direction", d.ToString())
 			next.Superposition = s
+			w.updateEntropy(next)
+			w.markCollapsed(next)
+			if w.OnPropagate != nil {
+				w.OnPropagate(previous, next, d)
+			}
 		}
 
 		// Check if we have a contradiction
 		if len(next.Superposition) == 0 {
+			w.logContradiction(next)
 			return ErrNoSolution
 		}
 
@@ -307,10 +890,18 @@ func (w *Wave) Recurse() error {
 
 // GetPossibleModules returns a list of modules that are possible when traveling
 // from slot "a" to slot "b" with the provided direction.
+//
+// With DefaultIsPossibleFunc the result only depends on the modules in both
+// slots, so it is memoized by the superposition of slot "a" and the direction.
 func (w *Wave) GetPossibleModules(a, b *Slot, d Direction) []*Module {
+	if res, ok := w.cachedPossibleModules(a, b, d); ok {
+		return res
+	}
+
+	w.countChecks(len(b.Superposition))
 	res := make([]*Module, 0)
 	for _, m := range b.Superposition {
-		if w.IsPossibleFn(m, a, b, d) {
+		if w.isPossible(m, a, b, d) {
 			res = append(res, m)
 		}
 	}
@@ -319,8 +910,12 @@ func (w *Wave) GetPossibleModules(a, b *Slot, d Direction) []*Module {
 	return res
 }
 
-// GetSlot returns the slot at the given coordinates in this wave function.
+// GetSlot returns the slot at the given coordinates in this wave function, or
+// nil if the wave isn't initialized, e.g. after Release.
 func (w *Wave) GetSlot(x, y int) *Slot {
+	if w.PossibilitySpace == nil {
+		return nil
+	}
 	return w.PossibilitySpace[x+y*w.Width]
 }
 
@@ -346,52 +941,172 @@ func (w *Wave) IsCollapsed() bool {
 	return true
 }
 
+// TotalPossibilities returns the number of modules left in all superpositions
+// together, a measure of how much freedom remains in the wave. It equals the
+// number of slots once the wave is fully collapsed without contradictions.
+func (w *Wave) TotalPossibilities() int {
+	total := 0
+	for _, s := range w.PossibilitySpace {
+		total += len(s.Superposition)
+	}
+	return total
+}
+
 // HasNeighbor checks if the given slot has a neighbor in the given direction
-// (edges of the grid don't have neighbors).
+// (edges of the grid don't have neighbors, unless the grid wraps around).
 func (w *Wave) HasNeighbor(s *Slot, d Direction) bool {
 	switch d {
 	case Up:
-		return s.Y > 0
+		return s.Y > 0 || (w.WrapY && w.Height > 1)
 	case Down:
-		return s.Y < w.Height-1
+		return s.Y < w.Height-1 || (w.WrapY && w.Height > 1)
 	case Left:
-		return s.X > 0
+		return s.X > 0 || (w.WrapX && w.Width > 1)
 	case Right:
-		return s.X < w.Width-1
+		return s.X < w.Width-1 || (w.WrapX && w.Width > 1)
 	}
 	return false
 }
 
-// GetNeighbor returns the slot in the given direction from the given slot.
+// GetNeighbor returns the slot in the given direction from the given slot, or
+// nil if the wave isn't initialized, see GetSlot.
 func (w *Wave) GetNeighbor(s *Slot, d Direction) *Slot {
 	switch d {
 	case Up:
-		return w.GetSlot(s.X, s.Y-1)
+		return w.GetSlot(s.X, (s.Y-1+w.Height)%w.Height)
 	case Down:
-		return w.GetSlot(s.X, s.Y+1)
+		return w.GetSlot(s.X, (s.Y+1)%w.Height)
 	case Left:
-		return w.GetSlot(s.X-1, s.Y)
+		return w.GetSlot((s.X-1+w.Width)%w.Width, s.Y)
 	case Right:
-		return w.GetSlot(s.X+1, s.Y)
+		return w.GetSlot((s.X+1)%w.Width, s.Y)
 	}
 	return nil
 }
 
+// TileSize returns the width and height in pixels of the input tiles, which is
+// the size of every slot in the exported images. Returns ErrEmptyInput if
+// there is no input tile with an image.
+func (w *Wave) TileSize() (int, int, error) {
+	u, v := w.tileSize()
+	if u == 0 && v == 0 {
+		return 0, 0, ErrEmptyInput
+	}
+	return u, v, nil
+}
+
+// tileSize returns the size of the first input tile with an image, or 0, 0 if
+// there is none. All tiles are expected to share this size, see Validate.
+func (w *Wave) tileSize() (int, int) {
+	for _, m := range w.Input {
+		if m.Image != nil {
+			b := m.Image.Bounds()
+			return b.Dx(), b.Dy()
+		}
+	}
+	return 0, 0
+}
+
+// TransparentTileMode is how ExportImage renders fully transparent tiles.
+type TransparentTileMode int
+
+const (
+	// TransparentTileNone draws nothing, like the tile itself.
+	TransparentTileNone TransparentTileMode = iota
+
+	// TransparentTileCheckerboard fills the tile with a gray checkerboard.
+	TransparentTileCheckerboard
+
+	// TransparentTileOutline draws a one pixel gray outline around the tile.
+	TransparentTileOutline
+)
+
+// CheckExportSize returns ErrExportTooLarge if the image rendered by
+// ExportImage would have more pixels than MaxExportPixels allows, or a size
+// that overflows. Exports that are too large return an empty image instead of
+// attempting the allocation, so call this first to validate user supplied
+// grid and tile sizes.
+func (w *Wave) CheckExportSize() error {
+	limit := w.MaxExportPixels
+	if limit == 0 {
+		limit = DefaultMaxExportPixels
+	}
+	if limit < 0 {
+		// image.NewRGBA needs 4 bytes per pixel
+		limit = math.MaxInt / 4
+	}
+
+	u, v := w.tileSize()
+	if w.Width < 0 || w.Height < 0 ||
+		(u > 0 && w.Width > math.MaxInt/u) || (v > 0 && w.Height > math.MaxInt/v) {
+		return fmt.Errorf("%dx%d slots of %dx%d pixels: %w", w.Width, w.Height, u, v, ErrExportTooLarge)
+	}
+	width, height := w.Width*u, w.Height*v
+	if width > 0 && height > limit/width {
+		return fmt.Errorf("%dx%d pixels, at most %d allowed: %w", width, height, limit, ErrExportTooLarge)
+	}
+	return nil
+}
+
+// placeholderColor is the color of the tiles drawn for modules without an
+// image, like the gray rectangles of ExportSVG.
+var placeholderColor = color.RGBA{128, 128, 128, 255}
+
+// exportRect returns the bounds of the image rendered by ExportImage, or an
+// empty rectangle if it would be too large, see CheckExportSize.
+func (w *Wave) exportRect() image.Rectangle {
+	if w.CheckExportSize() != nil {
+		return image.Rectangle{}
+	}
+	u, v := w.tileSize()
+	return image.Rect(0, 0, w.Width*u, w.Height*v)
+}
+
 // Export takes the current state of the wave collapse function and exports it
 // as an image. Any slots that have not been collapsed will be transparent.
-// Contradictions will be red.
+// Contradictions will be red, unless ContradictionRender provides an image for
+// them. If GridLines is set, lines are drawn between the tiles to make their
+// boundaries visible. Set TransparentTiles to tell slots collapsed into a
+// transparent tile apart from uncollapsed ones. Modules without an image, e.g.
+// symbolic ones mixed with tiles, are drawn as gray tiles. Returns an empty
+// image if the image would be too large, see CheckExportSize.
 func (w *Wave) ExportImage() image.Image {
-	u := w.Input[0].Image.Bounds().Max.X
-	v := w.Input[0].Image.Bounds().Max.Y
-	img := image.NewRGBA(image.Rect(0, 0, w.Width*u, w.Height*v))
+	u, v := w.tileSize()
+	img := image.NewRGBA(w.exportRect())
+	if img.Rect.Empty() {
+		return img
+	}
 
+	transparent := make(map[*Module]bool)
 	for _, s := range w.PossibilitySpace {
 		if len(s.Superposition) == 1 {
-			draw.Draw(img,
-				image.Rect(s.X*u, s.Y*v, (s.X+1)*u, (s.Y+1)*v),
-				s.Superposition[0].Image, image.ZP, draw.Over)
+			r := image.Rect(s.X*u, s.Y*v, (s.X+1)*u, (s.Y+1)*v)
+			m := s.Superposition[0]
+			if m.Image == nil {
+				draw.Draw(img, r, image.NewUniform(placeholderColor), image.Point{}, draw.Src)
+				continue
+			}
+			draw.Draw(img, r, m.Image, m.Image.Bounds().Min, draw.Over)
+
+			if w.TransparentTiles != TransparentTileNone {
+				t, ok := transparent[m]
+				if !ok {
+					t = tileIsTransparent(m.Image)
+					transparent[m] = t
+				}
+				if t {
+					w.drawTransparentMarker(img, r)
+				}
+			}
 		}
 		if len(s.Superposition) == 0 {
+			if w.ContradictionRender != nil {
+				if tile := w.ContradictionRender(s); tile != nil {
+					r := image.Rect(s.X*u, s.Y*v, (s.X+1)*u, (s.Y+1)*v)
+					draw.Draw(img, r, tile, tile.Bounds().Min, draw.Over)
+					continue
+				}
+			}
 			c := color.RGBA{255, 0, 0, 255}
 			for x := s.X * u; x < (s.X+1)*u; x++ {
 				for y := s.Y * v; y < (s.Y+1)*v; y++ {
@@ -401,5 +1116,82 @@ func (w *Wave) ExportImage() image.Image {
 		}
 	}
 
+	if w.GridLines > 0 {
+		w.drawGridLines(img, u, v)
+	}
+
 	return img
 }
+
+// drawGridLines draws lines centered on the boundaries between tiles. For an
+// odd thickness, the tile after the boundary gets the extra pixel.
+func (w *Wave) drawGridLines(img *image.RGBA, u, v int) {
+	c := w.GridColor
+	if c == nil {
+		c = color.Black
+	}
+	src := image.NewUniform(c)
+	b := img.Bounds()
+	half := w.GridLines / 2
+
+	for i := 1; i < w.Width; i++ {
+		x := i*u - half
+		r := image.Rect(x, b.Min.Y, x+w.GridLines, b.Max.Y)
+		draw.Draw(img, r, src, image.Point{}, draw.Src)
+	}
+	for j := 1; j < w.Height; j++ {
+		y := j*v - half
+		r := image.Rect(b.Min.X, y, b.Max.X, y+w.GridLines)
+		draw.Draw(img, r, src, image.Point{}, draw.Src)
+	}
+}
+
+// drawTransparentMarker marks the given tile rectangle according to the
+// TransparentTiles mode.
+func (w *Wave) drawTransparentMarker(img *image.RGBA, r image.Rectangle) {
+	light := color.RGBA{204, 204, 204, 255}
+	dark := color.RGBA{153, 153, 153, 255}
+
+	for x := r.Min.X; x < r.Max.X; x++ {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			switch w.TransparentTiles {
+			case TransparentTileCheckerboard:
+				if ((x-r.Min.X)/4+(y-r.Min.Y)/4)%2 == 0 {
+					img.Set(x, y, light)
+				} else {
+					img.Set(x, y, dark)
+				}
+			case TransparentTileOutline:
+				if x == r.Min.X || x == r.Max.X-1 || y == r.Min.Y || y == r.Max.Y-1 {
+					img.Set(x, y, dark)
+				}
+			}
+		}
+	}
+}
+
+// Equal returns true if both waves have the same dimensions and every slot
+// holds the same modules (compared by index). Partially collapsed waves are
+// only equal if all their superpositions match, not just the collapsed slots.
+func (w *Wave) Equal(other *Wave) bool {
+	if w.Width != other.Width || w.Height != other.Height {
+		return false
+	}
+	if len(w.PossibilitySpace) != len(other.PossibilitySpace) {
+		return false
+	}
+
+	for i, s := range w.PossibilitySpace {
+		o := other.PossibilitySpace[i]
+		if len(s.Superposition) != len(o.Superposition) {
+			return false
+		}
+		for j, m := range s.Superposition {
+			if m.Index != o.Superposition[j].Index {
+				return false
+			}
+		}
+	}
+
+	return true
+}
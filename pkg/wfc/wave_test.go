@@ -0,0 +1,104 @@
+package wfc
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestTileSize(t *testing.T) {
+	small := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	large := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	sheet := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	sub := sheet.SubImage(image.Rect(16, 8, 22, 13))
+
+	tests := []struct {
+		name   string
+		images []image.Image
+		u, v   int
+	}{
+		{"empty input", nil, 0, 0},
+		{"symbolic", []image.Image{nil, nil}, 0, 0},
+		{"leading symbolic", []image.Image{nil, small}, 4, 3},
+		{"sub-image", []image.Image{sub}, 6, 5},
+		{"mixed sizes", []image.Image{small, large}, 4, 3},
+	}
+
+	for _, tt := range tests {
+		modules := make([]*Module, len(tt.images))
+		for i, img := range tt.images {
+			modules[i] = &Module{Index: i, Image: img}
+		}
+		w := NewFromModules(modules, 1, 1)
+
+		if u, v := w.tileSize(); u != tt.u || v != tt.v {
+			t.Errorf("%s: tileSize() = %d, %d, want %d, %d", tt.name, u, v, tt.u, tt.v)
+		}
+
+		u, v, err := w.TileSize()
+		if tt.u == 0 && tt.v == 0 {
+			if !errors.Is(err, ErrEmptyInput) {
+				t.Errorf("%s: TileSize() error = %v, want ErrEmptyInput", tt.name, err)
+			}
+		} else if err != nil || u != tt.u || v != tt.v {
+			t.Errorf("%s: TileSize() = %d, %d, %v, want %d, %d", tt.name, u, v, err, tt.u, tt.v)
+		}
+	}
+}
+
+func TestValidateMixedTileSizes(t *testing.T) {
+	w := NewFromModules([]*Module{
+		{Index: 0, Image: image.NewRGBA(image.Rect(0, 0, 4, 4))},
+		{Index: 1},
+		{Index: 2, Image: image.NewRGBA(image.Rect(0, 0, 4, 5))},
+	}, 1, 1)
+
+	if err := w.validateTileSizes(); !errors.Is(err, ErrTileSizeMismatch) {
+		t.Errorf("validateTileSizes() = %v, want ErrTileSizeMismatch", err)
+	}
+}
+
+func TestCollapseIter(t *testing.T) {
+	all := func(a, b int, d Direction) bool { return true }
+	none := func(a, b int, d Direction) bool { return false }
+
+	tests := []struct {
+		name  string
+		rule  func(a, b int, d Direction) bool
+		stop  int
+		steps int
+		err   error
+	}{
+		{"collapsed", all, -1, 9, nil},
+		{"stopped", all, 2, 2, nil},
+		{"contradiction", none, -1, 0, ErrNoSolution},
+	}
+
+	for _, tt := range tests {
+		w := NewSymbolic(2, tt.rule, 3, 3)
+		w.Initialize(1)
+		steps := 0
+		err := w.CollapseIter(func(state *Wave) bool {
+			steps++
+			return steps != tt.stop
+		})
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: CollapseIter() = %v, want %v", tt.name, err, tt.err)
+		}
+		if steps > tt.steps {
+			t.Errorf("%s: %d steps, want at most %d", tt.name, steps, tt.steps)
+		}
+		if tt.err == nil && tt.stop < 0 && !w.IsCollapsed() {
+			t.Errorf("%s: wave is not collapsed", tt.name)
+		}
+
+		w.Initialize(1)
+		var last error
+		for _, err := range w.All() {
+			last = err
+		}
+		if !errors.Is(last, tt.err) {
+			t.Errorf("%s: All() ended with %v, want %v", tt.name, last, tt.err)
+		}
+	}
+}
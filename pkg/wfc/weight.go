@@ -0,0 +1,202 @@
+package wfc
+
+// DynamicWeightFunc returns the weight of a module when collapsing a slot,
+// given how many slots have already been collapsed into that module (placed)
+// and how many slots have been collapsed in total.
+//
+// The result is multiplied with the static Module.Weight. Returning smaller
+// values as placed grows softly limits the over-use of a module, without the
+// hard cut-off of a count limit.
+type DynamicWeightFunc func(m *Module, placed int, total int) float64
+
+// TagAffinity adds a soft rule between modules tagged tagA and modules tagged
+// tagB (see Module.Tags), e.g. to make forest tiles prefer forest neighbors
+// without forbidding anything else. When collapsing a slot, the weight of a
+// module with one of the tags is multiplied by weight for every neighbor that
+// is collapsed into a module with the other tag, so values above 1 attract
+// and values between 0 and 1 repel. The rule is symmetric; adding it again
+// for the same tags replaces it, and 1 removes it.
+func (w *Wave) TagAffinity(tagA, tagB string, weight float64) {
+	if w.tagAffinity == nil {
+		w.tagAffinity = make(map[[2]string]float64)
+	}
+	w.tagAffinity[[2]string{tagA, tagB}] = weight
+	w.tagAffinity[[2]string{tagB, tagA}] = weight
+}
+
+// collapseWeighted collapses a slot into a random module, taking the module
+// weights into account. If all weights are equal this is the same as an
+// unweighted pick.
+func (w *Wave) collapseWeighted(s *Slot) {
+	weights := w.moduleWeights(s)
+
+	uniform := true
+	for _, wt := range weights {
+		if wt != weights[0] {
+			uniform = false
+			break
+		}
+	}
+	if uniform {
+		s.collapseWith(w.rng)
+		return
+	}
+
+	sum := 0.0
+	for _, wt := range weights {
+		sum += wt
+	}
+
+	r := w.rng.Float64() * sum
+	for i, wt := range weights {
+		r -= wt
+		if r < 0 {
+			s.Superposition = []*Module{s.Superposition[i]}
+			return
+		}
+	}
+	s.Superposition = []*Module{s.Superposition[len(s.Superposition)-1]}
+}
+
+// moduleWeights returns the weight of every module in the superposition of the
+// given slot.
+func (w *Wave) moduleWeights(s *Slot) []float64 {
+	if w.DynamicWeightFn != nil && w.placed == nil {
+		w.countPlaced()
+	}
+	total := len(w.placed)
+
+	var previous *Module
+	if w.RepetitionPenalty != 0 && len(w.decisions) > 0 {
+		last := w.decisions[len(w.decisions)-1]
+		if p := w.GetSlot(last.X, last.Y); len(p.Superposition) == 1 {
+			previous = p.Superposition[0]
+		}
+	}
+
+	weights := make([]float64, len(s.Superposition))
+	for i, m := range s.Superposition {
+		wt := m.weight()
+		if o, ok := w.weights[m]; ok {
+			wt = o
+		}
+		if w.DynamicWeightFn != nil {
+			wt *= w.DynamicWeightFn(m, w.placedCount[m], total)
+		}
+		if w.CohesionBias != 0 {
+			wt *= 1 + w.CohesionBias*float64(w.matchingNeighbors(s, m))
+		}
+		if w.DirectionalWeight != nil {
+			wt *= w.directionalWeight(s, m)
+		}
+		if len(w.tagAffinity) > 0 && len(m.Tags) > 0 {
+			wt *= w.affinityWeight(s, m)
+		}
+		if m == previous {
+			wt *= 1 - w.RepetitionPenalty
+		}
+		if wt < 0 {
+			wt = 0
+		}
+		weights[i] = wt
+	}
+
+	return weights
+}
+
+// countPlaced counts the collapsed slots per module from scratch. The counts
+// are then kept up to date by recordPlaced as slots collapse, and dropped
+// whenever slots may lose their module, e.g. when backtracking restores an
+// earlier state, to be counted again on next use.
+func (w *Wave) countPlaced() {
+	w.placed = make(map[*Slot]*Module)
+	w.placedCount = make(map[*Module]int)
+	for _, s := range w.PossibilitySpace {
+		w.recordPlaced(s)
+	}
+}
+
+// recordPlaced updates the counts of countPlaced for a slot that may have
+// collapsed. It is a no-op while nothing is counted. Recording a slot again is
+// safe, its previous module is replaced.
+func (w *Wave) recordPlaced(s *Slot) {
+	if w.placed == nil || len(s.Superposition) != 1 {
+		return
+	}
+	m := s.Superposition[0]
+	if old, ok := w.placed[s]; ok {
+		if old == m {
+			return
+		}
+		w.placedCount[old]--
+	}
+	w.placed[s] = m
+	w.placedCount[m]++
+}
+
+// forgetPlaced removes a slot that is about to lose its module from the counts
+// of countPlaced. It is a no-op while nothing is counted.
+func (w *Wave) forgetPlaced(s *Slot) {
+	if m, ok := w.placed[s]; ok {
+		delete(w.placed, s)
+		w.placedCount[m]--
+	}
+}
+
+// matchingNeighbors counts the neighbors of a slot that are collapsed into the
+// given module.
+func (w *Wave) matchingNeighbors(s *Slot, m *Module) int {
+	count := 0
+	for _, d := range Directions {
+		if !w.HasNeighbor(s, d) {
+			continue
+		}
+		n := w.GetNeighbor(s, d)
+		if len(n.Superposition) == 1 && n.Superposition[0] == m {
+			count++
+		}
+	}
+	return count
+}
+
+// directionalWeight returns the product of the DirectionalWeight of every
+// direction in which the slot has a neighbor collapsed into the given module.
+// The directions are visited in a fixed order so the floating point product,
+// and with it the collapse, is reproducible for a seed.
+func (w *Wave) directionalWeight(s *Slot, m *Module) float64 {
+	wt := 1.0
+	for _, d := range Directions {
+		f, ok := w.DirectionalWeight[d]
+		if !ok || !w.HasNeighbor(s, d) {
+			continue
+		}
+		n := w.GetNeighbor(s, d)
+		if len(n.Superposition) == 1 && n.Superposition[0] == m {
+			wt *= f
+		}
+	}
+	return wt
+}
+
+// affinityWeight returns the product of the TagAffinity weights between the
+// tags of the given module and the tags of the collapsed neighbors of the slot.
+func (w *Wave) affinityWeight(s *Slot, m *Module) float64 {
+	wt := 1.0
+	for _, d := range Directions {
+		if !w.HasNeighbor(s, d) {
+			continue
+		}
+		n := w.GetNeighbor(s, d)
+		if len(n.Superposition) != 1 {
+			continue
+		}
+		for _, a := range m.Tags {
+			for _, b := range n.Superposition[0].Tags {
+				if f, ok := w.tagAffinity[[2]string{a, b}]; ok {
+					wt *= f
+				}
+			}
+		}
+	}
+	return wt
+}